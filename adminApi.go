@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"trawler/pkg/api/admin"
+	revocationapi "trawler/pkg/api/revocation"
+	"trawler/pkg/archive"
+	cfg "trawler/pkg/config"
+	logging "trawler/pkg/logging"
+	"trawler/pkg/status"
+)
+
+const (
+	defaultAdminPort = 8081
+	refreshQueueSize = 10
+)
+
+var crlStatus = status.NewStore() // Status of each CRL served by the admin API
+
+// refreshRequest asks the CRL retrieval worker to process CRLs immediately
+type refreshRequest struct {
+	names []string // Names of the CRLs to process, all CRLs if empty
+}
+
+// refreshRequests is read by crlRetrievalWorker, so that refreshes never run concurrently with a scheduled cycle
+var refreshRequests = make(chan refreshRequest, refreshQueueSize)
+
+// requestRefresh queues a refresh without waiting for the worker
+func requestRefresh(names []string) error {
+	select {
+	case refreshRequests <- refreshRequest{names: names}:
+		if len(names) == 0 {
+			logging.LogToConsole(logging.InfoLevel, logging.InfoEvent, "Refresh of all CRLs requested through the admin API")
+		} else {
+			logging.LogToConsole(logging.InfoLevel, logging.InfoEvent, fmt.Sprintf("Refresh of CRL %s requested through the admin API", strings.Join(names, ", ")))
+		}
+		return nil
+	default:
+		return admin.ErrRefreshQueueFull
+	}
+}
+
+// refreshCRLs processes the requested CRLs regardless of their schedule
+func refreshCRLs(config *cfg.Config, request refreshRequest, errChannel chan<- logging.ErrorReport) {
+	if len(request.names) == 0 {
+		processOnlineCRLs(config, config.Configurations.OnlineCrls, errChannel)
+		processOfflineCRLs(config, errChannel)
+		checkExpiry(config, errChannel)
+		return
+	}
+
+	requested := make(map[string]bool)
+	for _, name := range request.names {
+		requested[name] = true
+	}
+	var onlineCrls []cfg.OnlineCrl
+	for _, onlineCrl := range config.Configurations.OnlineCrls {
+		if requested[onlineCrl.Name] {
+			onlineCrls = append(onlineCrls, onlineCrl)
+		}
+	}
+	if len(onlineCrls) > 0 {
+		processOnlineCRLs(config, onlineCrls, errChannel)
+	}
+	for _, offlineCrl := range config.Configurations.OfflineCrls {
+		if requested[offlineCrl.Name] {
+			processOfflineCRL(config, offlineCrl, errChannel)
+		}
+	}
+	commitCRLs(config)
+	checkExpiry(config, errChannel)
+} // func refreshCRLs
+
+// registerCRLStatus adds the configured CRLs to the status store, and removes CRLs no longer in config
+func registerCRLStatus(config *cfg.Config) {
+	var definitions []status.Definition
+	for _, onlineCrl := range config.Configurations.OnlineCrls {
+		kind := status.KindOnline
+		if onlineCrl.IsDelta() {
+			kind = status.KindDelta
+		}
+		definitions = append(definitions, status.Definition{Name: onlineCrl.Name, Kind: kind, Source: onlineCrl.URL})
+	}
+	for _, offlineCrl := range config.Configurations.OfflineCrls {
+		crlFileName := offlineCrl.CrlFileName
+		if crlFileName == "" {
+			crlFileName = offlineCrl.Name + ".crl"
+		}
+		definitions = append(definitions, status.Definition{Name: offlineCrl.Name, Kind: status.KindOffline, Source: config.Configurations.Global.OfflineCrlsPath + crlFileName})
+	}
+	crlStatus.Sync(definitions)
+} // func registerCRLStatus
+
+// pruneRevocationIndex removes CRLs no longer in config from the revocation index
+func pruneRevocationIndex(config *cfg.Config) {
+	configured := make(map[string]bool)
+	for _, onlineCrl := range config.Configurations.OnlineCrls {
+		configured[onlineCrlCacheName(onlineCrl)] = true
+	}
+	for _, offlineCrl := range config.Configurations.OfflineCrls {
+		configured[offlineCrl.Name] = true
+	}
+	for _, name := range revocationIndex.Names() {
+		if !configured[name] {
+			revocationIndex.Remove(name)
+		}
+	}
+} // func pruneRevocationIndex
+
+// startAdminServer serves the admin API until stopChan is closed.
+// The API can trigger fetches, so it is not started without a way to authenticate.
+func startAdminServer(config *cfg.Config, stopChan <-chan struct{}) error {
+	adminConfig := config.Configurations.Admin
+	token := adminConfig.Token
+	if token == "" {
+		token = os.Getenv("ADMIN_API_TOKEN")
+	}
+	if token == "" && adminConfig.ClientCAFile == "" {
+		return fmt.Errorf("admin API requires a token or a client CA for mTLS")
+	}
+	port := adminConfig.Port
+	if port <= 0 {
+		port = defaultAdminPort
+	}
+
+	handler := admin.NewHandler(crlStatus, requestRefresh, token)
+	handler.Handle(revocationapi.Path, revocationapi.NewHandler(revocationIndex))
+	handler.HandleArchive(archive.NewReader(currentStorageBackends), func() bool {
+		return cfg.Current().Configurations.Archive.Enabled
+	})
+	return admin.StartAdminServer(admin.ServerConfig{
+		Port:         port,
+		TLSCertFile:  adminConfig.TLSCertFile,
+		TLSKeyFile:   adminConfig.TLSKeyFile,
+		ClientCAFile: adminConfig.ClientCAFile,
+	}, stopChan, handler)
+} // func startAdminServer
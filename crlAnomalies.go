@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	cfg "trawler/pkg/config"
+	"trawler/pkg/crl"
+	logging "trawler/pkg/logging"
+)
+
+// checkCRLAnomalies compares a validated CRL with the version currently served, using the rules of the CRL.
+// Anomalies raise a critical alert. It returns true if the CRL is quarantined and must not be served or published.
+func checkCRLAnomalies(ctx context.Context, config *cfg.Config, publication *crlPublication, crlRules *cfg.AnomalyRules, errChannel chan<- logging.ErrorReport) bool {
+	ruleConfig := config.Configurations.Anomalies
+	if crlRules != nil {
+		ruleConfig = *crlRules
+	}
+	rules := crl.AnomalyRules{
+		MaxNewRevocations:  ruleConfig.MaxNewRevocations,
+		MaxGrowthPercent:   ruleConfig.MaxGrowthPercent,
+		AlertOnRemovals:    ruleConfig.AlertOnRemovals,
+		MaxSizeDropPercent: ruleConfig.MaxSizeDropPercent,
+		MaxCRLNumberJump:   ruleConfig.MaxCRLNumberJump,
+		MaxClockSkew:       time.Duration(ruleConfig.MaxClockSkewMinutes) * time.Minute,
+	}
+	alertKey := fmt.Sprintf("anomaly/%s", publication.Name)
+	fields := []logging.Field{logging.CRL(publication.Name), logging.Hash(publication.Hash)}
+
+	if !rules.Enabled() {
+		crlStatus.ClearQuarantine(publication.Name)
+		errChannel <- logging.ResolvedReport(alertKey)
+		return false
+	}
+
+	var diff *crl.Diff
+	previousCRL := servedCRL(ctx, publication)
+	if previousCRL != nil {
+		diff = crl.DiffCRLs(previousCRL, publication.Decoded)
+	}
+	anomalies := crl.DetectAnomalies(previousCRL, publication.Decoded, diff, rules, time.Now())
+	if len(anomalies) == 0 {
+		crlStatus.ClearQuarantine(publication.Name)
+		errChannel <- logging.ResolvedReport(alertKey)
+		return false
+	}
+
+	messages := make([]string, 0, len(anomalies))
+	for _, anomaly := range anomalies {
+		messages = append(messages, anomaly.String())
+	}
+	if ruleConfig.Quarantine && crlStatus.QuarantineReleased(publication.Name, publication.Hash) {
+		logging.LogFields(logging.WarningLevel, logging.WarningEvent, fmt.Sprintf("CRL %s was released from quarantine, publishing it despite anomalies: %s", publication.Name, strings.Join(messages, "; ")), fields...)
+		crlStatus.ClearQuarantine(publication.Name)
+		errChannel <- logging.ResolvedReport(alertKey)
+		return false
+	}
+
+	alertContext := fmt.Sprintf("Anomalies detected in CRL %s from %s", publication.Name, publication.Source)
+	if ruleConfig.Quarantine {
+		crlNumber := ""
+		if publication.Decoded.Number != nil {
+			crlNumber = publication.Decoded.Number.String()
+		}
+		crlStatus.RecordQuarantine(publication.Name, publication.Hash, crlNumber, messages)
+		alertContext += ", quarantined instead of published"
+	}
+	details := strings.Join(messages, "\n")
+	if diff != nil {
+		details += "\n\n" + diff.Details()
+	}
+	errChannel <- logging.ErrorReport{
+		Err:         errors.New(strings.Join(messages, "; ")),
+		Context:     alertContext,
+		Severity:    logging.SeverityCritical,
+		Criticality: logging.CriticalityHigh,
+		Fields:      fields,
+		Key:         alertKey,
+		Details:     details,
+	}
+	return ruleConfig.Quarantine
+} // func checkCRLAnomalies
+
+// servedCRL returns the version of a CRL that is currently served, or else the version in the first storage backend holding one.
+// It returns nil if no previous version is known.
+func servedCRL(ctx context.Context, publication *crlPublication) *x509.RevocationList {
+	if entry, exists := crlCache.Get(strings.TrimSuffix(publication.ObjectKey(), ".crl")); exists {
+		return entry.Decoded()
+	}
+	for _, backend := range currentStorageBackends() {
+		data, err := backend.Get(ctx, publication.ObjectKey())
+		if err != nil {
+			continue
+		}
+		storedCRL, err := crl.ParseCertificateRevocationList(data)
+		if err != nil {
+			continue
+		}
+		return storedCRL
+	}
+	return nil
+} // func servedCRL
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+	"trawler/pkg/archive"
+	cfg "trawler/pkg/config"
+	logging "trawler/pkg/logging"
+	"trawler/pkg/storage"
+)
+
+var crlArchiver = archive.NewArchiver(archive.Policy{}) // Keeps every distinct CRL version in the storage backends
+
+// initCRLArchive applies the retention policy from config
+func initCRLArchive(config *cfg.Config) {
+	archiveConfig := config.Configurations.Archive
+	crlArchiver.SetPolicy(archive.Policy{
+		MaxVersions: archiveConfig.MaxVersions,
+		MaxAge:      time.Duration(archiveConfig.MaxAgeDays) * 24 * time.Hour,
+	})
+}
+
+// archiveCRL stores the published CRL as a version in the archive of the backend
+func archiveCRL(ctx context.Context, backend storage.Backend, publication *crlPublication, errChannel chan<- logging.ErrorReport) {
+	fields := []logging.Field{logging.CRL(publication.Name), logging.Backend(backend.Name()), logging.Hash(publication.Hash)}
+	alertKey := fmt.Sprintf("archive/%s/%s", backend.Name(), publication.Name)
+
+	archived, err := crlArchiver.Archive(ctx, backend, publication.Name, publication.Raw, publication.Decoded)
+	if err != nil {
+		errChannel <- logging.ErrorReport{
+			Err:         err,
+			Context:     fmt.Sprintf("[%s] Error archiving CRL %s", backend.Name(), publication.Name),
+			Severity:    logging.SeverityWarning,
+			Criticality: logging.CriticalityLow,
+			Fields:      fields,
+			Key:         alertKey,
+		}
+		return
+	}
+	errChannel <- logging.ResolvedReport(alertKey)
+	if archived {
+		logging.LogFields(logging.InfoLevel, logging.InfoEvent, fmt.Sprintf("[%s] CRL %s archived", backend.Name(), publication.Name), fields...)
+	}
+} // func archiveCRL
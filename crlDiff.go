@@ -0,0 +1,50 @@
+package main
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"strings"
+	"trawler/pkg/crl"
+	logging "trawler/pkg/logging"
+	"trawler/pkg/storage"
+)
+
+// diffCRL compares the CRL being published with the version stored in the backend before it.
+// The comparison is done once per publication, against the first backend holding a different version.
+// The diff is logged and recorded for the admin API, and raises an alert when revoked certificates
+// disappear without explanation or the CRL is signed by a different issuer or key.
+func diffCRL(backend storage.Backend, publication *crlPublication, storedCRL *x509.RevocationList, errChannel chan<- logging.ErrorReport) *crl.Diff {
+	if publication.Diff != nil {
+		return publication.Diff
+	}
+	diff := crl.DiffCRLs(storedCRL, publication.Decoded)
+	publication.Diff = diff
+	crlStatus.RecordDiff(publication.Name, diff)
+
+	fields := []logging.Field{logging.CRL(publication.Name), logging.Backend(backend.Name()), logging.Hash(publication.Hash)}
+	logging.LogFields(logging.InfoLevel, logging.InfoEvent, fmt.Sprintf("[%s] Changes in CRL %s: %s", backend.Name(), publication.Name, diff), append(fields, logging.Diff(diff))...)
+
+	alertKey := fmt.Sprintf("diff/%s", publication.Name)
+	var unexpected []string
+	if suspicious := diff.SuspiciousRemovals(); suspicious > 0 {
+		unexpected = append(unexpected, fmt.Sprintf("%d revoked certificates were removed from the CRL", suspicious))
+	}
+	if diff.Signer != nil {
+		unexpected = append(unexpected, "the CRL is signed by a different issuer, key or algorithm")
+	}
+	if len(unexpected) == 0 {
+		errChannel <- logging.ResolvedReport(alertKey)
+		return diff
+	}
+	errChannel <- logging.ErrorReport{
+		Err:         errors.New(strings.Join(unexpected, "; ")),
+		Context:     fmt.Sprintf("Unexpected changes in CRL %s from %s", publication.Name, publication.Source),
+		Severity:    logging.SeverityWarning,
+		Criticality: logging.CriticalityMedium,
+		Fields:      fields,
+		Key:         alertKey,
+		Details:     diff.Details(),
+	}
+	return diff
+} // func diffCRL
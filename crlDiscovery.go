@@ -0,0 +1,235 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	cfg "trawler/pkg/config"
+	crl "trawler/pkg/crl"
+	"trawler/pkg/discovery"
+	helpers "trawler/pkg/helpers"
+	logging "trawler/pkg/logging"
+)
+
+// discoverCRLs returns a copy of config whose online CRLs include the CRLs named in the distribution points of the
+// online CA certificates and the watched leaf certificates, replacing those discovered before. The copy is made the
+// configuration in effect, and config itself is left unchanged since other goroutines may be reading it.
+// CRLs listed in the config file take precedence over discovered CRLs with the same name or URL.
+// A certificate names the CRL of its issuer, so the CRL of a CA that has issued none of the certificates read here,
+// such as a newly added intermediate, is looked for next to the CRL of its issuer by findSelfCRLs.
+// Certificates issued by an offline CA are skipped, since offline CRLs are published from offlineCrls.
+func discoverCRLs(config *cfg.Config) *cfg.Config {
+	var explicitCrls, previouslyDiscovered []cfg.OnlineCrl
+	for _, onlineCrl := range config.Configurations.OnlineCrls {
+		if onlineCrl.Discovered {
+			previouslyDiscovered = append(previouslyDiscovered, onlineCrl)
+		} else {
+			explicitCrls = append(explicitCrls, onlineCrl)
+		}
+	}
+
+	var discoveredCrls []cfg.OnlineCrl
+	if config.Configurations.Discovery.Enabled {
+		discoveredCrls = findDiscoveredCRLs(config, explicitCrls)
+	}
+	discoveredConfig := *config
+	discoveredConfig.Configurations.OnlineCrls = append(explicitCrls, discoveredCrls...)
+	config = &discoveredConfig
+	cfg.SetCurrent(config)
+
+	if discoveredCrlsEqual(previouslyDiscovered, discoveredCrls) {
+		return config
+	}
+	for _, onlineCrl := range discoveredCrls {
+		logging.LogFields(logging.InfoLevel, logging.InfoEvent, fmt.Sprintf("Discovered CRL %s, signed by %s", onlineCrl.Name, onlineCrl.CertFileName),
+			logging.CRL(onlineCrl.Name), logging.URL(onlineCrl.URL))
+	}
+	logging.LogToConsole(logging.InfoLevel, logging.InfoEvent, fmt.Sprintf("Monitoring %d configured and %d discovered online CRLs.", len(explicitCrls), len(discoveredCrls)))
+	registerCRLHealthChecks(config)
+	registerCRLStatus(config)
+	pruneRevocationIndex(config)
+	return config
+} // func discoverCRLs
+
+// findDiscoveredCRLs returns the online CRLs found in the certificates that are not configured explicitly
+func findDiscoveredCRLs(config *cfg.Config, explicitCrls []cfg.OnlineCrl) []cfg.OnlineCrl {
+	global := config.Configurations.Global
+	caCertificates, err := discovery.ReadCertificates(global.OnlineCAStoragePath)
+	if err != nil {
+		logging.LogToConsole(logging.WarningLevel, logging.WarningEvent, fmt.Sprintf("Error reading CA certificates for CRL discovery from %s: %v", global.OnlineCAStoragePath, err))
+		return nil
+	}
+	var offlineCertificates []discovery.Certificate
+	if global.OfflineCAStoragePath != "" {
+		offlineCertificates, err = discovery.ReadCertificates(global.OfflineCAStoragePath)
+		if err != nil {
+			logging.LogToConsole(logging.WarningLevel, logging.WarningEvent, fmt.Sprintf("Error reading offline CA certificates for CRL discovery from %s: %v", global.OfflineCAStoragePath, err))
+		}
+	}
+
+	certificates := append([]discovery.Certificate{}, caCertificates...)
+	if leafPath := config.Configurations.Discovery.LeafCertificatePath; leafPath != "" {
+		leafCertificates, err := discovery.ReadCertificates(leafPath)
+		if err != nil {
+			logging.LogToConsole(logging.WarningLevel, logging.WarningEvent, fmt.Sprintf("Error reading leaf certificates for CRL discovery from %s: %v", leafPath, err))
+		}
+		certificates = append(certificates, leafCertificates...)
+	}
+
+	var onlineIssued []discovery.Certificate
+	for _, certificate := range certificates {
+		if discovery.FindIssuer(offlineCertificates, certificate.Certificate) == nil {
+			onlineIssued = append(onlineIssued, certificate)
+		}
+	}
+
+	found, errs := discovery.Discover(caCertificates, onlineIssued)
+	for _, err := range errs {
+		logging.LogToConsole(logging.WarningLevel, logging.WarningEvent, fmt.Sprintf("CRL discovery: %v", err))
+	}
+	found = append(found, findSelfCRLs(config, caCertificates, found, explicitCrls)...)
+
+	configured := make(map[string]bool, 2*len(explicitCrls))
+	for _, onlineCrl := range explicitCrls {
+		configured[onlineCrl.Name] = true
+		for _, crlUrl := range onlineCrl.URLs() {
+			configured[strings.ToLower(crlUrl)] = true
+		}
+	}
+	var discoveredCrls []cfg.OnlineCrl
+	for _, discoveredCrl := range found {
+		if configured[discoveredCrl.Name] || configured[strings.ToLower(discoveredCrl.URL)] {
+			continue
+		}
+		// A delta CRL whose base is configured explicitly under another name is linked to that name
+		baseCrl := discoveredCrl.BaseCrl
+		for _, onlineCrl := range explicitCrls {
+			if baseCrl != "" && !onlineCrl.IsDelta() && strings.EqualFold(onlineCrl.URL, baseURL(found, baseCrl)) {
+				baseCrl = onlineCrl.Name
+			}
+		}
+		discoveredCrls = append(discoveredCrls, cfg.OnlineCrl{
+			Name:         discoveredCrl.Name,
+			URL:          discoveredCrl.URL,
+			Mirrors:      discoveredCrl.Mirrors,
+			CertFileName: discoveredCrl.CertFileName,
+			BaseCrl:      baseCrl,
+			Discovered:   true,
+		})
+	}
+	return discoveredCrls
+} // func findDiscoveredCRLs
+
+// selfCRLs holds the CRLs found next to the CRL of the issuer of a CA by the fingerprint of the CA certificate,
+// so that each is only fetched and verified once
+var selfCRLs = make(map[string]discovery.CRL)
+
+// selfCRLsMissing holds the fingerprints of the CA certificates whose CRL could not be found, so that it is logged once
+var selfCRLsMissing = make(map[string]bool)
+
+// findSelfCRLs returns the CRLs of the online CAs that have issued none of the certificates read for discovery,
+// such as a newly added intermediate. Such a CRL is looked for next to the CRL of the issuer of the CA, and only
+// returned once it has been retrieved and verified to be signed by the CA.
+func findSelfCRLs(config *cfg.Config, caCertificates []discovery.Certificate, found []discovery.CRL, explicitCrls []cfg.OnlineCrl) []discovery.CRL {
+	hasCRL := make(map[string]bool) // CA certificate files with a CRL
+	crlNames := make(map[string]bool)
+	for _, discoveredCrl := range found {
+		hasCRL[discoveredCrl.CertFileName] = true
+		crlNames[discoveredCrl.Name] = true
+	}
+	for _, onlineCrl := range explicitCrls {
+		hasCRL[onlineCrl.CertFileName] = true
+		crlNames[onlineCrl.Name] = true
+	}
+
+	var selfFound []discovery.CRL
+	for _, caCertificate := range caCertificates {
+		if hasCRL[caCertificate.FileName] {
+			continue
+		}
+		crlUrls := discovery.SelfCRLURLs(caCertificate.Certificate)
+		if len(crlUrls) == 0 {
+			continue
+		}
+		fingerprint := helpers.ComputeHash(caCertificate.Certificate.Raw)
+		selfCrl, verified := selfCRLs[fingerprint]
+		if !verified {
+			var err error
+			selfCrl, err = verifySelfCRL(config, caCertificate, crlUrls)
+			if err != nil {
+				if !selfCRLsMissing[fingerprint] {
+					selfCRLsMissing[fingerprint] = true
+					logging.LogToConsole(logging.WarningLevel, logging.WarningEvent, fmt.Sprintf("CRL discovery: no CRL of CA %s (%s) found, add a certificate it issued to the leaf certificate path or list its CRL in onlineCrls: %v",
+						caCertificate.Certificate.Subject, caCertificate.FileName, err))
+				}
+				continue
+			}
+			selfCRLs[fingerprint] = selfCrl
+			delete(selfCRLsMissing, fingerprint)
+		}
+		if crlNames[selfCrl.Name] {
+			continue
+		}
+		crlNames[selfCrl.Name] = true
+		hasCRL[caCertificate.FileName] = true
+		selfFound = append(selfFound, selfCrl)
+	}
+	return selfFound
+} // func findSelfCRLs
+
+// verifySelfCRL returns the first of the URLs serving a CRL signed by the CA certificate
+func verifySelfCRL(config *cfg.Config, caCertificate discovery.Certificate, crlUrls []string) (discovery.CRL, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), fetchTimeout(config))
+	defer cancel()
+
+	var errs []error
+	for _, crlUrl := range crlUrls {
+		name, err := discovery.NameFromURL(crlUrl)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		fetchResult, err := crlFetcher.Fetch(ctx, crlUrl)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", crlUrl, err))
+			continue
+		}
+		revocationList, err := crl.ParseCertificateRevocationList(fetchResult.Data)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", crlUrl, err))
+			continue
+		}
+		if err := revocationList.CheckSignatureFrom(caCertificate.Certificate); err != nil {
+			errs = append(errs, fmt.Errorf("%s: CRL is not signed by the CA: %w", crlUrl, err))
+			continue
+		}
+		return discovery.CRL{Name: name, URL: crlUrl, CertFileName: caCertificate.FileName, FoundIn: caCertificate.FileName}, nil
+	}
+	return discovery.CRL{}, errors.Join(errs...)
+} // func verifySelfCRL
+
+// baseURL returns the URL of the discovered CRL with the given name
+func baseURL(found []discovery.CRL, name string) string {
+	for _, discoveredCrl := range found {
+		if discoveredCrl.Name == name {
+			return discoveredCrl.URL
+		}
+	}
+	return ""
+} // func baseURL
+
+// discoveredCrlsEqual reports whether two lists of discovered CRLs describe the same CRLs
+func discoveredCrlsEqual(a []cfg.OnlineCrl, b []cfg.OnlineCrl) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Name != b[i].Name || a[i].URL != b[i].URL || !slices.Equal(a[i].Mirrors, b[i].Mirrors) ||
+			a[i].CertFileName != b[i].CertFileName || a[i].BaseCrl != b[i].BaseCrl {
+			return false
+		}
+	}
+	return true
+} // func discoveredCrlsEqual
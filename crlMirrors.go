@@ -0,0 +1,237 @@
+package main
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	cfg "trawler/pkg/config"
+	crl "trawler/pkg/crl"
+	helpers "trawler/pkg/helpers"
+	logging "trawler/pkg/logging"
+	"trawler/pkg/metrics"
+)
+
+// crlCandidate is a CRL retrieved from one of the URLs of an online CRL
+type crlCandidate struct {
+	URL                string
+	Raw                []byte // Nil if the CRL could not be retrieved
+	Decoded            *x509.RevocationList
+	NextPublish        bool
+	NextPublishTime    time.Time
+	ValidationDuration time.Duration
+	Signer             *crl.CACertificate
+	SignerRelation     string // How the signer relates to the configured certificate, empty if the CRL was not parsed
+	Err                error  // Retrieval, parsing or validation error, errCRLNotValid for an invalid CRL
+}
+
+// fetchCandidate retrieves the CRL from a single URL and validates it against its signer among the CA certificates
+func fetchCandidate(ctx context.Context, onlineCrl cfg.OnlineCrl, crlUrl string, caCertificates []crl.CACertificate) *crlCandidate {
+	candidate := &crlCandidate{URL: crlUrl}
+	crlFields := []logging.Field{logging.CRL(onlineCrl.Name), logging.URL(crlUrl)}
+
+	// Read out the raw CRL data from the crl retrieved from the URL
+	fetchStart := time.Now()
+	fetchResult, err := crlFetcher.Fetch(ctx, crlUrl)
+	if err != nil {
+		metrics.ObserveFetch(onlineCrl.Name, metrics.OutcomeError, time.Since(fetchStart))
+		crlStatus.RecordFetch(onlineCrl.Name, metrics.OutcomeError, err)
+		candidate.Err = fmt.Errorf("error retrieving CRL from %s: %w", crlUrl, err)
+		return candidate
+	}
+	fetchDuration := time.Since(fetchStart)
+	if fetchResult.NotModified {
+		metrics.ObserveFetch(onlineCrl.Name, metrics.OutcomeNotModified, fetchDuration)
+		crlStatus.RecordFetch(onlineCrl.Name, metrics.OutcomeNotModified, nil)
+		logging.LogFields(logging.DebugLevel, logging.DebugEvent, fmt.Sprintf("CRL from %s not modified since last fetch, using cached copy.", crlUrl), append(crlFields, logging.Duration(fetchDuration))...)
+	} else {
+		metrics.ObserveFetch(onlineCrl.Name, metrics.OutcomeSuccess, fetchDuration)
+		crlStatus.RecordFetch(onlineCrl.Name, metrics.OutcomeSuccess, nil)
+		logging.LogFields(logging.DebugLevel, logging.DebugEvent, fmt.Sprintf("CRL retrieved from %s (%d bytes).", crlUrl, len(fetchResult.Data)), append(crlFields, logging.Duration(fetchDuration))...)
+	}
+	candidate.Raw = fetchResult.Data
+
+	validateStart := time.Now()
+	defer func() {
+		candidate.ValidationDuration = time.Since(validateStart)
+	}()
+
+	// Parse the raw CRL data into a structured format from ASN.1 DER or PEM, and keep the DER for publication
+	decodedCRL, err := crl.ParseCertificateRevocationList(candidate.Raw)
+	if err != nil {
+		candidate.Err = fmt.Errorf("error parsing CRL from %s: %w", crlUrl, err)
+		return candidate
+	}
+	candidate.Raw = decodedCRL.Raw
+
+	// Make sure the CRL is of the kind (base or delta) that is configured
+	_, isDelta, err := crl.DeltaCRLBaseNumber(decodedCRL)
+	if err != nil {
+		candidate.Err = err
+		return candidate
+	}
+	if onlineCrl.IsDelta() && !isDelta {
+		candidate.Err = fmt.Errorf("CRL from %s is configured as delta of %s, but has no Delta CRL Indicator", crlUrl, onlineCrl.BaseCrl)
+		return candidate
+	} else if !onlineCrl.IsDelta() && isDelta {
+		candidate.Err = fmt.Errorf("CRL from %s has a Delta CRL Indicator, but is not configured with baseCrl", crlUrl)
+		return candidate
+	}
+	candidate.Decoded = decodedCRL
+
+	// Validate the CRL against the certificate that signed it, if it belongs to the CA defined in config, and timestamps
+	signer, relation, err := resolveCRLSigner(caCertificates, onlineCrl.CertFileName, decodedCRL)
+	candidate.Signer, candidate.SignerRelation = signer, relation
+	if err != nil {
+		candidate.Err = fmt.Errorf("CRL from %s: %w", crlUrl, err)
+		return candidate
+	}
+	valid, nextPublish, nextPublishTime, err := crl.IsCRLValid(decodedCRL, signer.Certificate)
+	if err != nil {
+		candidate.Err = fmt.Errorf("error validating CRL from %s: %w", crlUrl, err)
+		return candidate
+	}
+	candidate.NextPublish, candidate.NextPublishTime = nextPublish, nextPublishTime
+	if !valid {
+		candidate.Err = errCRLNotValid
+	}
+	return candidate
+} // func fetchCandidate
+
+// fetchWithFailover tries the URL and then the mirrors of a CRL in order, and returns the first valid CRL.
+// If none is valid, the result from the last URL is returned.
+// Serving the CRL from a mirror raises a warning, resolved once the primary URL works again.
+func fetchWithFailover(ctx context.Context, onlineCrl cfg.OnlineCrl, caCertificates []crl.CACertificate, errChannel chan<- logging.ErrorReport) *crlCandidate {
+	alertKey := fmt.Sprintf("mirrors/%s", onlineCrl.Name)
+	var primaryErr error
+	var candidate *crlCandidate
+	for i, crlUrl := range onlineCrl.URLs() {
+		if i > 0 {
+			logging.LogFields(logging.WarningLevel, logging.WarningEvent, fmt.Sprintf("Failing over to mirror %s of CRL %s: %v", crlUrl, onlineCrl.Name, candidate.Err),
+				logging.CRL(onlineCrl.Name), logging.URL(crlUrl), logging.Err(candidate.Err))
+		}
+		candidate = fetchCandidate(ctx, onlineCrl, crlUrl, caCertificates)
+		if i == 0 {
+			primaryErr = candidate.Err
+		}
+		if candidate.Err == nil || ctx.Err() != nil {
+			break
+		}
+	}
+
+	if len(onlineCrl.Mirrors) == 0 {
+		return candidate
+	}
+	if primaryErr == nil || candidate.Err != nil {
+		// Either the primary URL works, or the CRL is not available at all, which is reported as a processing error
+		errChannel <- logging.ResolvedReport(alertKey)
+		return candidate
+	}
+	errChannel <- logging.ErrorReport{
+		Err:         primaryErr,
+		Context:     fmt.Sprintf("CRL %s retrieved from mirror %s instead of %s", onlineCrl.Name, candidate.URL, onlineCrl.URL),
+		Severity:    logging.SeverityWarning,
+		Criticality: logging.CriticalityLow,
+		Fields:      []logging.Field{logging.CRL(onlineCrl.Name), logging.URL(onlineCrl.URL)},
+		Key:         alertKey,
+	}
+	return candidate
+} // func fetchWithFailover
+
+// fetchFromAllMirrors retrieves a CRL from its URL and all mirrors, and returns the newest valid CRL.
+// Mirrors that fail, serve an expired CRL, or serve a different CRL than the newest raise a warning.
+// If no mirror serves a valid CRL, the result from the URL is returned.
+func fetchFromAllMirrors(ctx context.Context, onlineCrl cfg.OnlineCrl, caCertificates []crl.CACertificate, errChannel chan<- logging.ErrorReport) *crlCandidate {
+	alertKey := fmt.Sprintf("mirrors/%s", onlineCrl.Name)
+	urls := onlineCrl.URLs()
+	candidates := make([]*crlCandidate, 0, len(urls))
+	for _, crlUrl := range urls {
+		candidates = append(candidates, fetchCandidate(ctx, onlineCrl, crlUrl, caCertificates))
+	}
+
+	var newest *crlCandidate
+	for _, candidate := range candidates {
+		if candidate.Err == nil && (newest == nil || newerCRL(candidate.Decoded, newest.Decoded)) {
+			newest = candidate
+		}
+	}
+
+	var disagreements []string
+	var details strings.Builder
+	now := time.Now()
+	for _, candidate := range candidates {
+		state := "newest"
+		switch {
+		case candidate.Decoded == nil:
+			state = fmt.Sprintf("failed: %v", candidate.Err)
+		case now.After(candidate.Decoded.NextUpdate):
+			state = fmt.Sprintf("expired at %s", candidate.Decoded.NextUpdate.UTC().Format(time.RFC3339))
+		case candidate.Err != nil:
+			state = fmt.Sprintf("invalid: %v", candidate.Err)
+		case candidate == newest:
+			// Compared with itself
+		case !crlNumbersEqual(candidate.Decoded, newest.Decoded):
+			state = fmt.Sprintf("serves CRL Number %s instead of %s", crlNumberString(candidate.Decoded), crlNumberString(newest.Decoded))
+		case helpers.ComputeHash(candidate.Raw) != helpers.ComputeHash(newest.Raw):
+			state = "serves a different CRL with the same CRL Number"
+		default:
+			state = "identical"
+		}
+		if state != "newest" && state != "identical" {
+			disagreements = append(disagreements, fmt.Sprintf("%s %s", candidate.URL, state))
+		}
+		fmt.Fprintf(&details, "%s: %s", candidate.URL, state)
+		if candidate.Decoded != nil {
+			fmt.Fprintf(&details, " (CRL Number %s, ThisUpdate %s, hash %s)", crlNumberString(candidate.Decoded),
+				candidate.Decoded.ThisUpdate.UTC().Format(time.RFC3339), helpers.ComputeHash(candidate.Raw))
+		}
+		details.WriteString("\n")
+	}
+
+	if len(disagreements) == 0 {
+		errChannel <- logging.ResolvedReport(alertKey)
+	} else {
+		errChannel <- logging.ErrorReport{
+			Err:         errors.New(strings.Join(disagreements, "; ")),
+			Context:     fmt.Sprintf("Mirrors of CRL %s disagree", onlineCrl.Name),
+			Severity:    logging.SeverityWarning,
+			Criticality: logging.CriticalityMedium,
+			Fields:      []logging.Field{logging.CRL(onlineCrl.Name)},
+			Key:         alertKey,
+			Details:     details.String(),
+		}
+	}
+
+	if newest == nil {
+		return candidates[0]
+	}
+	if newest.URL != onlineCrl.URL {
+		logging.LogFields(logging.InfoLevel, logging.InfoEvent, fmt.Sprintf("Using CRL %s from mirror %s, which serves the newest version", onlineCrl.Name, newest.URL),
+			logging.CRL(onlineCrl.Name), logging.URL(newest.URL))
+	}
+	return newest
+} // func fetchFromAllMirrors
+
+// newerCRL reports whether a CRL is newer than another, by CRL Number and then ThisUpdate
+func newerCRL(candidate *x509.RevocationList, current *x509.RevocationList) bool {
+	if candidate.Number != nil && current.Number != nil && candidate.Number.Cmp(current.Number) != 0 {
+		return candidate.Number.Cmp(current.Number) > 0
+	}
+	return candidate.ThisUpdate.After(current.ThisUpdate)
+} // func newerCRL
+
+func crlNumbersEqual(a *x509.RevocationList, b *x509.RevocationList) bool {
+	if a.Number == nil || b.Number == nil {
+		return a.Number == nil && b.Number == nil
+	}
+	return a.Number.Cmp(b.Number) == 0
+} // func crlNumbersEqual
+
+func crlNumberString(revocationList *x509.RevocationList) string {
+	if revocationList.Number == nil {
+		return "none"
+	}
+	return revocationList.Number.String()
+} // func crlNumberString
@@ -2,20 +2,22 @@ package main
 
 import (
 	"context"
+	"crypto/x509"
+	"errors"
 	"fmt"
-	"io"
-	"net/url"
-	"os"
+	"reflect"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 	cfg "trawler/pkg/config"
 	crl "trawler/pkg/crl"
 	git "trawler/pkg/git"
-	"trawler/pkg/health"
 	helpers "trawler/pkg/helpers"
 	logging "trawler/pkg/logging"
+	"trawler/pkg/metrics"
 	"trawler/pkg/storage"
-	"trawler/pkg/storage/s3"
+	"trawler/pkg/vault"
 )
 
 func crlRetrievalWorker(config *cfg.Config, errChannel chan<- logging.ErrorReport, stopChan <-chan struct{}) (err error) {
@@ -25,6 +27,10 @@ func crlRetrievalWorker(config *cfg.Config, errChannel chan<- logging.ErrorRepor
 	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
+	// With adaptive scheduling, online CRLs are processed when due instead of on the poll interval
+	scheduleTicker := time.NewTicker(scheduleCheckInterval)
+	defer scheduleTicker.Stop()
+
 	// Run once immediately, then on interval
 	//TODO: Implement checking if Git-storage is enabled and if so, perform a sync before the first run
 	err = git.CopyItemsToLocalStorage(config)
@@ -33,7 +39,11 @@ func crlRetrievalWorker(config *cfg.Config, errChannel chan<- logging.ErrorRepor
 	} else {
 		logging.LogToConsole(logging.InfoLevel, logging.InfoEvent, "Successfully copied from Git repository to local storage.")
 	}
+	config = discoverCRLs(config)
 	processCRLs(config, errChannel)
+	processOfflineCRLs(config, errChannel)
+	checkExpiry(config, errChannel)
+	recordWorkerHeartbeat()
 
 	for {
 		select {
@@ -41,14 +51,35 @@ func crlRetrievalWorker(config *cfg.Config, errChannel chan<- logging.ErrorRepor
 			// Clean shutdown signal received
 			logging.LogToConsole(logging.InfoLevel, logging.InfoEvent, "Graceful shutdown of Trawler.")
 			return
+		case request := <-refreshRequests:
+			// Refresh requested through the admin API
+			refreshCRLs(config, request, errChannel)
+			recordWorkerHeartbeat()
+		case <-scheduleTicker.C:
+			if config.Configurations.Scheduling.Enabled {
+				processCRLs(config, errChannel)
+			}
+			recordWorkerHeartbeat()
 		case <-ticker.C:
 			// On each tick, refresh config and process CRLs
-			configRenewed, err := cfg.RefreshConfig(config, configPath, config.Configurations.Global.PollIntervalMinutes)
+			var configRenewed bool
+			config, configRenewed, err = cfg.RefreshConfig(config, configPath, config.Configurations.Global.PollIntervalMinutes)
 			if err != nil {
 				logging.LogToConsole(logging.ErrorLevel, logging.ErrorEvent, fmt.Sprintf("Error refreshing config: %v", err))
 			}
 			if configRenewed {
+				cfg.SetCurrent(config)
+				logging.Configure(config.Configurations.Global.LogLevel, config.Configurations.Global.OutputFormat)
 				logging.LogToConsole(logging.InfoLevel, logging.InfoEvent, "Configuration refreshed successfully.")
+				initCRLFetcher(config)
+				updateStorageBackends(config)
+				initCRLArchive(config)
+				registerStorageHealthChecks(config)
+				registerCRLHealthChecks(config)
+				registerCRLStatus(config)
+				pruneRevocationIndex(config)
+				loadOCSPSigners(config)
+				presignOCSPResponses(config)
 			} else {
 				logging.LogToConsole(logging.InfoLevel, logging.InfoEvent, "Configuration not refreshed, no changes detected.")
 			}
@@ -59,213 +90,492 @@ func crlRetrievalWorker(config *cfg.Config, errChannel chan<- logging.ErrorRepor
 			} else {
 				logging.LogToConsole(logging.InfoLevel, logging.InfoEvent, "Successfully copied from Git repository to local storage.")
 			}
+			config = discoverCRLs(config)
+			pruneCRLMetrics(config)
 			// Execute on interval
-			processCRLs(config, errChannel)
+			if !config.Configurations.Scheduling.Enabled {
+				processCRLs(config, errChannel)
+			}
+			processOfflineCRLs(config, errChannel)
+			checkExpiry(config, errChannel)
+			recordWorkerHeartbeat()
 		}
 	}
 }
 
-func processCRLs(config *cfg.Config, errChannel chan<- logging.ErrorReport) error {
+// Defaults for the worker pool, used when not set in config
+const (
+	defaultMaxConcurrentFetches = 4
+	defaultFetchTimeoutSeconds  = 60
+)
+
+// processCRLs processes all online CRLs that are due concurrently in a bounded pool of workers.
+// Each CRL is isolated, so an error for one CRL is reported without affecting the others.
+func processCRLs(config *cfg.Config, errChannel chan<- logging.ErrorReport) {
 	// Loop through all online CRLs defined in the config file
-	for i := 0; i < len(config.Configurations.OnlineCrls); i++ {
+	var dueCrls []cfg.OnlineCrl
+	now := time.Now()
+	for _, onlineCrl := range config.Configurations.OnlineCrls {
+		if scheduler.IsDue(config, onlineCrl.Name, now) {
+			dueCrls = append(dueCrls, onlineCrl)
+		}
+	}
+	processOnlineCRLs(config, dueCrls, errChannel)
+} // func processCRLs
 
-		crlUrl := config.Configurations.OnlineCrls[i].URL // Get the URL from the config file
-		infoMsgCRL := fmt.Sprintf("Processing CRL from URL: %s", crlUrl)
-		logging.LogToConsole(logging.InfoLevel, logging.InfoEvent, strings.Repeat("-", len(infoMsgCRL)))
-		logging.LogToConsole(logging.InfoLevel, logging.InfoEvent, infoMsgCRL)
-		logging.LogToConsole(logging.InfoLevel, logging.InfoEvent, strings.Repeat("-", len(infoMsgCRL)))
+// processOnlineCRLs processes the given online CRLs, regardless of when they are due, and serves the valid ones.
+// Base CRLs are processed before delta CRLs, so that a delta is never published ahead of its base.
+func processOnlineCRLs(config *cfg.Config, onlineCrls []cfg.OnlineCrl, errChannel chan<- logging.ErrorReport) {
+	var baseCrls, deltaCrls []cfg.OnlineCrl
+	for _, onlineCrl := range onlineCrls {
+		if onlineCrl.IsDelta() {
+			deltaCrls = append(deltaCrls, onlineCrl)
+		} else {
+			baseCrls = append(baseCrls, onlineCrl)
+		}
+	}
 
-		// Read out the raw CRL data from the crl retrieved from the above URL
-		rawCRL, err := crl.RetrieveCertificateRevocationList(crlUrl)
-		if err != nil {
-			logging.LogToConsole(logging.ErrorLevel, logging.ErrorEvent, fmt.Sprintf("Error retrieving CRL: %v", err))
-			return nil
+	failures := processCRLBatch(config, baseCrls, errChannel)
+	failures += processCRLBatch(config, deltaCrls, errChannel)
+
+	// Serve the CRLs validated in this cycle
+	commitCRLs(config)
+
+	if len(baseCrls)+len(deltaCrls) > 0 && failures == 0 {
+		metrics.SetLastSuccessfulCycle(time.Now())
+	}
+} // func processOnlineCRLs
+
+// processCRLBatch processes the given CRLs concurrently and waits for all of them to finish.
+// It returns the number of CRLs that could not be processed.
+func processCRLBatch(config *cfg.Config, onlineCrls []cfg.OnlineCrl, errChannel chan<- logging.ErrorReport) int {
+	maxConcurrentFetches := config.Configurations.Global.MaxConcurrentFetches
+	if maxConcurrentFetches <= 0 {
+		maxConcurrentFetches = defaultMaxConcurrentFetches
+	}
+
+	semaphore := make(chan struct{}, maxConcurrentFetches)
+	var crlWg sync.WaitGroup
+	var failures atomic.Int32
+
+	for _, onlineCrl := range onlineCrls {
+		crlWg.Add(1)
+		semaphore <- struct{}{} // Blocks until a worker is available
+
+		go func(onlineCrl cfg.OnlineCrl) {
+			defer crlWg.Done()
+			defer func() { <-semaphore }()
+
+			ctx, cancel := context.WithTimeout(context.Background(), fetchTimeout(config))
+			defer cancel()
+
+			timestamps, err := processOnlineCRL(ctx, config, onlineCrl, errChannel)
+			recordCRLSourceResult(onlineCrl.Name, err)
+			recordWorkerHeartbeat()
+			nextFetch := scheduler.Schedule(config, onlineCrl.Name, timestamps.NextUpdate, timestamps.NextCRLPublish)
+			if config.Configurations.Scheduling.Enabled {
+				crlStatus.RecordNextFetch(onlineCrl.Name, nextFetch)
+				logging.LogFields(logging.DebugLevel, logging.DebugEvent, fmt.Sprintf("Next fetch of CRL %s scheduled at %s", onlineCrl.Name, nextFetch.Format(time.RFC3339)), logging.CRL(onlineCrl.Name))
+			}
+			alertKey := fmt.Sprintf("process/%s", onlineCrl.Name)
+			if err != nil {
+				failures.Add(1)
+			} else {
+				errChannel <- logging.ResolvedReport(alertKey)
+			}
+			// An invalid CRL has already been logged, and the previous valid CRL remains published
+			if err != nil && !errors.Is(err, errCRLNotValid) {
+				errChannel <- logging.ErrorReport{
+					Err:         err,
+					Context:     fmt.Sprintf("Error processing CRL %s from %s", onlineCrl.Name, onlineCrl.URL),
+					Severity:    logging.SeverityWarning,
+					Criticality: logging.CriticalityMedium,
+					Fields:      []logging.Field{logging.CRL(onlineCrl.Name), logging.URL(onlineCrl.URL)},
+					Key:         alertKey,
+				}
+			}
+		}(onlineCrl)
+	}
+
+	crlWg.Wait()
+	return int(failures.Load())
+} // func processCRLBatch
+
+// fetchTimeout returns the time each CRL may spend being fetched, validated and published
+func fetchTimeout(config *cfg.Config) time.Duration {
+	fetchTimeoutSeconds := config.Configurations.Global.FetchTimeoutSeconds
+	if fetchTimeoutSeconds <= 0 {
+		fetchTimeoutSeconds = defaultFetchTimeoutSeconds
+	}
+	return time.Duration(fetchTimeoutSeconds) * time.Second
+}
+
+// crlTimestamps holds the update times of a processed CRL, zero if the CRL could not be processed
+type crlTimestamps struct {
+	ThisUpdate     time.Time
+	NextUpdate     time.Time
+	NextCRLPublish time.Time // This is a ADCS (Microsoft) specific field and not part of the standard x509.RevocationList
+}
+
+// errCRLNotValid is returned for a CRL that failed validation against its CA certificate or timestamps
+var errCRLNotValid = errors.New("CRL is not valid")
+
+// processOnlineCRL retrieves, validates and publishes a single online CRL
+func processOnlineCRL(ctx context.Context, config *cfg.Config, onlineCrl cfg.OnlineCrl, errChannel chan<- logging.ErrorReport) (timestamps crlTimestamps, err error) {
+	crlFields := []logging.Field{logging.CRL(onlineCrl.Name), logging.URL(onlineCrl.URL)}
+	logging.LogFields(logging.InfoLevel, logging.InfoEvent, fmt.Sprintf("Processing CRL %s from URL: %s", onlineCrl.Name, onlineCrl.URL), crlFields...)
+
+	// The CA certificates are needed to validate the CRL from each of its URLs
+	caCertificates, err := readCACertificates(config.Configurations.Global.OnlineCAStoragePath, onlineCrl.CertFileName)
+	if err != nil {
+		crlStatus.RecordValidation(onlineCrl.Name, metrics.OutcomeError, err)
+		return timestamps, err
+	}
+
+	var candidate *crlCandidate
+	if onlineCrl.MirrorConsistency && len(onlineCrl.Mirrors) > 0 {
+		candidate = fetchFromAllMirrors(ctx, onlineCrl, caCertificates, errChannel)
+	} else {
+		candidate = fetchWithFailover(ctx, onlineCrl, caCertificates, errChannel)
+	}
+	reportCRLSigner(onlineCrl.Name, onlineCrl.CertFileName, candidate.Signer, candidate.SignerRelation, candidate.Err, errChannel)
+	if candidate.Decoded != nil && (candidate.Err == nil || errors.Is(candidate.Err, errCRLNotValid)) {
+		timestamps = crlTimestamps{
+			ThisUpdate:     candidate.Decoded.ThisUpdate,
+			NextUpdate:     candidate.Decoded.NextUpdate,
+			NextCRLPublish: candidate.NextPublishTime,
+		}
+	}
+	if candidate.Raw != nil {
+		// Parsing and all checks up to the signature validation count as validation
+		validateOutcome := metrics.OutcomeSuccess
+		if errors.Is(candidate.Err, errCRLNotValid) {
+			validateOutcome = metrics.OutcomeInvalid
+		} else if candidate.Err != nil {
+			validateOutcome = metrics.OutcomeError
 		}
+		metrics.ObserveValidation(onlineCrl.Name, validateOutcome, candidate.ValidationDuration)
+		crlStatus.RecordValidation(onlineCrl.Name, validateOutcome, candidate.Err)
+	}
+	if errors.Is(candidate.Err, errCRLNotValid) {
+		logging.LogFields(logging.WarningLevel, logging.WarningEvent, fmt.Sprintf("CRL from %s is NOT valid.", candidate.URL), crlFields...)
+		return timestamps, candidate.Err
+	} else if candidate.Err != nil {
+		return timestamps, candidate.Err
+	}
+
+	crlUrl := candidate.URL
+	crlFields = []logging.Field{logging.CRL(onlineCrl.Name), logging.URL(crlUrl)}
+	rawCRL, decodedCRL := candidate.Raw, candidate.Decoded
+	nextPublish, nextPublishTime := candidate.NextPublish, candidate.NextPublishTime
+	logging.LogFields(logging.InfoLevel, logging.InfoEvent, fmt.Sprintf("CRL from %s is valid.", crlUrl), append(crlFields, logging.Duration(candidate.ValidationDuration))...)
+	metrics.ObserveCRL(onlineCrl.Name, decodedCRL, nextPublishTime, len(rawCRL))
 
-		// Parse the raw CRL data into a structured format from ASN.1 DER
-		decodedCRL, err := crl.ParseCertificateRevocationList(rawCRL)
+	publication := newCRLPublication(onlineCrl.Name, crlUrl, rawCRL, decodedCRL)
+	crlStatus.RecordValid(onlineCrl.Name, publication.Hash, decodedCRL, nextPublishTime)
+	publication.BaseName = onlineCrl.BaseCrl
+	if checkCRLAnomalies(ctx, config, publication, onlineCrl.Anomalies, errChannel) {
+		return timestamps, nil
+	}
+	stageForDistribution(publication, errChannel)
+
+	var proceedToStore bool = false
+
+	switch nextPublish {
+	case false:
+		logging.LogFields(logging.DebugLevel, logging.DebugEvent, "CRL does not contain NextPublish (ADCS-specific)", crlFields...)
+		proceedToStore = true
+	case true:
+		logging.LogFields(logging.DebugLevel, logging.DebugEvent, fmt.Sprintf("CRL contains NextPublish (ADCS-specific). NextPublishTime: %v", nextPublishTime), crlFields...)
+
+		if time.Now().After(nextPublishTime) {
+			proceedToStore = true
+		}
+	}
+	if proceedToStore { // Store with selected storage backends
+		publishCRL(ctx, config, publication, errChannel)
+	}
+
+	return timestamps, nil
+} // func processOnlineCRL
+
+// publishCRL stores the raw CRL with all storage backends, skipping backends that already hold an identical copy.
+// Offline CRLs are published next to the online ones, so that a single location serves the whole chain.
+// With the archive enabled, every CRL accepted by a backend is also kept there as a version.
+func publishCRL(ctx context.Context, config *cfg.Config, publication *crlPublication, errChannel chan<- logging.ErrorReport) {
+	for _, backend := range currentStorageBackends() {
+		outcome := publishCRLToBackend(ctx, backend, publication, errChannel)
+		if config.Configurations.Archive.Enabled && (outcome == metrics.OutcomeSuccess || outcome == metrics.OutcomeUnchanged) {
+			archiveCRL(ctx, backend, publication, errChannel)
+		}
+	}
+} // func publishCRL
+
+// crlPublication holds a validated CRL that is about to be published
+type crlPublication struct {
+	Name     string
+	Source   string
+	Raw      []byte
+	Decoded  *x509.RevocationList
+	Hash     string
+	BaseName string    // Name of the base CRL if this is a delta CRL
+	Diff     *crl.Diff // Changes compared with the version stored before, set by the first backend holding one
+}
+
+func newCRLPublication(name string, source string, rawCRL []byte, decodedCRL *x509.RevocationList) *crlPublication {
+	return &crlPublication{
+		Name:    name,
+		Source:  source,
+		Raw:     rawCRL,
+		Decoded: decodedCRL,
+		Hash:    helpers.ComputeHash(rawCRL),
+	}
+}
+
+// onlineCrlCacheName returns the name an online CRL is served under, matching crlPublication.ObjectKey
+func onlineCrlCacheName(onlineCrl cfg.OnlineCrl) string {
+	if onlineCrl.IsDelta() {
+		return onlineCrl.BaseCrl + "+"
+	}
+	return onlineCrl.Name
+}
+
+// ObjectKey returns the key the CRL is stored under in the storage backends.
+// Delta CRLs use the ADCS convention of a "+" suffix on the name of their base.
+func (p *crlPublication) ObjectKey() string {
+	if p.BaseName != "" {
+		return fmt.Sprintf("%s+.crl", p.BaseName)
+	}
+	return fmt.Sprintf("%s.crl", p.Name)
+}
+
+// publishCRLToBackend stores the CRL in a single backend and returns the outcome
+func publishCRLToBackend(ctx context.Context, backend storage.Backend, publication *crlPublication, errChannel chan<- logging.ErrorReport) (outcome string) {
+	logPrefix := fmt.Sprintf("[%s]", backend.Name())
+	objectKey := publication.ObjectKey()
+	source := publication.Source
+	newHash := publication.Hash
+
+	fields := []logging.Field{logging.CRL(publication.Name), logging.Backend(backend.Name()), logging.Hash(newHash)}
+	alertKey := fmt.Sprintf("publish/%s/%s", backend.Name(), publication.Name)
+
+	publishStart := time.Now()
+	outcome = metrics.OutcomeError
+	var publishErr error
+	defer func() {
+		metrics.ObservePublish(publication.Name, backend.Name(), outcome, time.Since(publishStart))
+		crlStatus.RecordPublish(publication.Name, backend.Name(), outcome, newHash, publishErr)
+	}()
+
+	// Check if the object already exists
+	existingFileData, err := backend.Get(ctx, objectKey)
+	if err == nil && len(existingFileData) > 0 {
+		logging.LogFields(logging.DebugLevel, logging.DebugEvent, fmt.Sprintf("%s Existing CRL found at %s, comparing hashes.", logPrefix, objectKey), fields...)
+
+		existingHash := helpers.ComputeHash(existingFileData)
+		hashMaxLength := 25
+		logging.LogFields(logging.DebugLevel, logging.DebugEvent, fmt.Sprintf("%s %-*s %s", logPrefix, hashMaxLength, "Existing CRL Hash:", existingHash), fields...)
+		logging.LogFields(logging.DebugLevel, logging.DebugEvent, fmt.Sprintf("%s %-*s %s", logPrefix, hashMaxLength, "New CRL Hash:", newHash), fields...)
+
+		if existingHash == newHash {
+			outcome = metrics.OutcomeUnchanged
+			errChannel <- logging.ResolvedReport(alertKey)
+			logging.LogFields(logging.InfoLevel, logging.InfoEvent, fmt.Sprintf("%s No changes detected in CRL from %s, skipping save.", logPrefix, source), fields...)
+			return
+		}
+		logging.LogFields(logging.InfoLevel, logging.InfoEvent, fmt.Sprintf("%s Changes detected in CRL from %s, updating %s.", logPrefix, source, objectKey), fields...)
+
+		// Refuse to replace the published CRL with an older one
+		existingCRL, err := crl.ParseCertificateRevocationList(existingFileData)
 		if err != nil {
-			logging.LogToConsole(logging.ErrorLevel, logging.ErrorEvent, fmt.Sprintf("Error parsing CRL: %v", err))
-			return nil
+			logging.LogFields(logging.WarningLevel, logging.WarningEvent, fmt.Sprintf("%s Existing CRL at %s could not be parsed, it will be replaced: %v", logPrefix, objectKey, err), append(fields, logging.Err(err))...)
+		} else {
+			diff := diffCRL(backend, publication, existingCRL, errChannel)
+			if err := crl.CheckRollback(existingCRL, publication.Decoded); err != nil {
+				outcome = metrics.OutcomeRejected
+				publishErr = err
+				errChannel <- logging.ErrorReport{
+					Err:         err,
+					Context:     fmt.Sprintf("%s Refused to publish CRL %s from %s", logPrefix, publication.Name, source),
+					Severity:    logging.SeverityCritical,
+					Criticality: logging.CriticalityHigh,
+					Fields:      fields,
+					Key:         alertKey,
+					Details:     diff.Details(),
+				}
+				return
+			}
+		}
+	} else if err == nil || errors.Is(err, storage.ErrNotFound) {
+		logging.LogFields(logging.InfoLevel, logging.InfoEvent, fmt.Sprintf("%s CRL %s does not exist, will proceed to save new file.", logPrefix, objectKey), fields...)
+	} else {
+		// Without the existing CRL the rollback check cannot run, so nothing is published until it can be read
+		publishErr = fmt.Errorf("error reading existing CRL %s: %w", objectKey, err)
+		errChannel <- logging.ErrorReport{
+			Err:         publishErr,
+			Context:     fmt.Sprintf("%s Skipped publishing CRL %s from %s", logPrefix, publication.Name, source),
+			Severity:    logging.SeverityWarning,
+			Criticality: logging.CriticalityMedium,
+			Fields:      fields,
+			Key:         alertKey,
 		}
+		return
+	}
 
-		certFilePath := config.Configurations.Global.OnlineCAStoragePath + config.Configurations.OnlineCrls[i].CertFileName
-		certData, err := os.ReadFile(certFilePath)
+	// A delta CRL is only published once the backend holds a base it can be combined with
+	if publication.BaseName != "" {
+		err = checkPublishedBase(ctx, backend, publication)
 		if err != nil {
+			outcome = metrics.OutcomeRejected
+			publishErr = err
+			severity := logging.SeverityWarning
+			if !errors.Is(err, crl.ErrDeltaAheadOfBase) && !errors.Is(err, storage.ErrNotFound) {
+				severity = logging.SeverityCritical
+			}
 			errChannel <- logging.ErrorReport{
 				Err:         err,
-				Context:     fmt.Sprintf("Error reading certificate file: %v. Path: %s", err, certFilePath),
-				Severity:    logging.SeverityWarning,
-				Criticality: logging.CriticalityLow,
+				Context:     fmt.Sprintf("%s Holding back delta CRL %s until its base %s is published", logPrefix, publication.Name, publication.BaseName),
+				Severity:    severity,
+				Criticality: logging.CriticalityMedium,
+				Fields:      fields,
+				Key:         alertKey,
 			}
-			// logging.LogToConsole(logging.ErrorLevel, logging.ErrorEvent, fmt.Sprintf("Error reading certificate file: %v. Path: %s", err, config.Configurations.OnlineCrls[i].CertFile))
-			return nil
+			return
 		}
+	}
 
-		// Validate and save the CRL to defined path if valid
-		certDataParsed, err := crl.ParseCertificate(certData)
-		if err != nil {
-			logging.LogToConsole(logging.ErrorLevel, logging.ErrorEvent, fmt.Sprintf("Error parsing certificate file: %v", err))
-			return nil
-		}
+	err = backend.Put(ctx, objectKey, publication.Raw)
+	if err != nil {
+		publishErr = err
+		logging.LogFields(logging.ErrorLevel, logging.ErrorEvent, fmt.Sprintf("%s Error saving CRL: %v", logPrefix, err), append(fields, logging.Err(err), logging.Duration(time.Since(publishStart)))...)
+	} else {
+		outcome = metrics.OutcomeSuccess
+		errChannel <- logging.ResolvedReport(alertKey)
+		logging.LogFields(logging.InfoLevel, logging.InfoEvent, fmt.Sprintf("%s CRL saved to %s", logPrefix, objectKey), append(fields, logging.Duration(time.Since(publishStart)))...)
+	}
+	return outcome
+} // func publishCRLToBackend
 
-		valid, nextPublish, nextPublishTime, err := crl.IsCRLValid(decodedCRL, certDataParsed) // Validate the CRL against the certificate defined in config, and timestamps
-		if err != nil {
-			logging.LogToConsole(logging.ErrorLevel, logging.ErrorEvent, fmt.Sprintf("Error validating CRL: %v", err))
-		} else if valid {
-			logging.LogToConsole(logging.InfoLevel, logging.InfoEvent, fmt.Sprintf("CRL from %s is valid.", crlUrl))
+// crlFetcherConfig holds the settings crlFetcher was created with
+var crlFetcherConfig crl.FetcherConfig
 
-			var proceedToStore bool = false
+// initCRLFetcher (re)creates the CRL fetcher when its settings in config change, keeping the previous fetcher if the
+// settings are invalid. A new fetcher takes over the cached responses, so unchanged CRLs are not downloaded again.
+func initCRLFetcher(config *cfg.Config) {
+	fetcherConfig := config.Configurations.Fetcher
+	bindDN, bindPassword := fetcherConfig.LDAP.BindDN, ""
+	var err error
+	if fetcherConfig.LDAP.VaultPath != "" {
+		bindDN, bindPassword, err = readLDAPCredentialsFromVault(fetcherConfig.LDAP.VaultPath, bindDN)
+	}
+	settings := crl.FetcherConfig{
+		ConnectTimeout:   time.Duration(fetcherConfig.ConnectTimeoutSeconds) * time.Second,
+		ReadTimeout:      time.Duration(fetcherConfig.ReadTimeoutSeconds) * time.Second,
+		MaxRetries:       fetcherConfig.MaxRetries,
+		InitialBackoff:   time.Duration(fetcherConfig.InitialBackoffMilliseconds) * time.Millisecond,
+		MaxBackoff:       time.Duration(fetcherConfig.MaxBackoffSeconds) * time.Second,
+		MaxBodyBytes:     fetcherConfig.MaxBodyBytes,
+		ProxyURL:         fetcherConfig.ProxyURL,
+		CABundlePath:     fetcherConfig.CABundlePath,
+		ClientCertPath:   fetcherConfig.ClientCertPath,
+		ClientKeyPath:    fetcherConfig.ClientKeyPath,
+		LDAPDefaultHost:  fetcherConfig.LDAP.DefaultHost,
+		LDAPBindDN:       bindDN,
+		LDAPBindPassword: bindPassword,
+	}
+	if err == nil && crlFetcher != nil && reflect.DeepEqual(settings, crlFetcherConfig) {
+		return
+	}
+	var fetcher *crl.Fetcher
+	if err == nil {
+		fetcher, err = crl.NewFetcher(settings)
+	}
+	if err != nil {
+		logging.LogToConsole(logging.ErrorLevel, logging.ErrorEvent, fmt.Sprintf("Invalid fetcher configuration: %v", err))
+		if crlFetcher == nil {
+			crlFetcher, _ = crl.NewFetcher(crl.FetcherConfig{})
+		}
+		return
+	}
+	fetcher.KeepCache(crlFetcher)
+	crlFetcher, crlFetcherConfig = fetcher, settings
+} // func initCRLFetcher
 
-			switch nextPublish {
-			case false:
-				logging.LogToConsole(logging.DebugLevel, logging.DebugEvent, "CRL does not contain NextPublish (ADCS-specific)")
-				proceedToStore = true
-			case true:
-				logging.LogToConsole(logging.DebugLevel, logging.DebugEvent, fmt.Sprintf("CRL contains NextPublish (ADCS-specific). NextPublishTime: %v", nextPublishTime))
+// pruneCRLMetrics removes the metrics of CRLs that are no longer configured or discovered
+func pruneCRLMetrics(config *cfg.Config) {
+	var crlNames []string
+	for _, onlineCrl := range config.Configurations.OnlineCrls {
+		crlNames = append(crlNames, onlineCrl.Name)
+	}
+	for _, offlineCrl := range config.Configurations.OfflineCrls {
+		crlNames = append(crlNames, offlineCrl.Name)
+	}
+	metrics.RetainCRLs(crlNames)
+} // func pruneCRLMetrics
 
-				if time.Now().After(nextPublishTime) {
-					proceedToStore = true
-				}
-			default:
-				logging.LogToConsole(logging.WarningLevel, logging.WarningEvent, "Unexpected value for NextPublish, proceeding to store by default.")
-				proceedToStore = true
-			}
-			if proceedToStore { // Store with selected storage backends
-				// Local storage
-				if localStorageEnabled := config.Configurations.Global.LocalStorageEnabled; localStorageEnabled {
-					// Verify and save the CRL to a file
-					crlFilePath := fmt.Sprintf("%s%s.crl", config.Configurations.Global.OnlineCrlsPath, config.Configurations.OnlineCrls[i].Name)
-
-					existingFileData, err := os.ReadFile(crlFilePath) // Check if file already exists
-					if err == nil && len(existingFileData) > 0 {
-						logging.LogToConsole(logging.DebugLevel, logging.DebugEvent, fmt.Sprintf("[Local] Existing CRL file found at %s, comparing hashes.", crlFilePath))
-
-						existingHash := helpers.ComputeHash(existingFileData)
-						newHash := helpers.ComputeHash(rawCRL)
-						hashMaxLength := 25
-						logging.LogToConsole(logging.DebugLevel, logging.DebugEvent, fmt.Sprintf("%-*s %s", hashMaxLength, "Existing CRL Hash:", existingHash))
-						logging.LogToConsole(logging.DebugLevel, logging.DebugEvent, fmt.Sprintf("%-*s %s", hashMaxLength, "New CRL Hash:", newHash))
-
-						if existingHash == newHash {
-							logging.LogToConsole(logging.InfoLevel, logging.InfoEvent, fmt.Sprintf("[Local] No changes detected in CRL from %s, skipping save.", crlUrl))
-							// continue // Skip saving if no changes
-						} else {
-							logging.LogToConsole(logging.InfoLevel, logging.InfoEvent, fmt.Sprintf("[Local] Changes detected in CRL from %s, updating file.", crlUrl))
-							logging.LogToConsole(logging.DebugLevel, logging.DebugEvent, fmt.Sprintf("[Local] CRLFilePath to save to: %s", crlFilePath))
-							err = storage.SaveCRLToFile(crlFilePath, rawCRL)
-							if err != nil {
-								logging.LogToConsole(logging.ErrorLevel, logging.ErrorEvent, fmt.Sprintf("[Local] Error saving CRL to file: %v", err))
-							} else {
-								logging.LogToConsole(logging.InfoLevel, logging.InfoEvent, fmt.Sprintf("[Local] CRL saved to %s", crlFilePath))
-							}
-						}
-					} else if err != nil && os.IsNotExist(err) {
-						logging.LogToConsole(logging.InfoLevel, logging.InfoEvent, fmt.Sprintf("[Local] CRL file %s does not exist, will proceed to save new file.", crlFilePath))
-						err = storage.SaveCRLToFile(crlFilePath, rawCRL)
-						if err != nil {
-							logging.LogToConsole(logging.ErrorLevel, logging.ErrorEvent, fmt.Sprintf("[Local] Error saving CRL to file: %v", err))
-						} else {
-							logging.LogToConsole(logging.InfoLevel, logging.InfoEvent, fmt.Sprintf("[Local] CRL saved to %s", crlFilePath))
-						}
-					} else if err != nil {
-						logging.LogToConsole(logging.ErrorLevel, logging.ErrorEvent, fmt.Sprintf("[Local] Error checking for existing CRL file: %v", err))
-					}
-				}
-				// S3 storage
-				fileExist := false
-				saveNewFile := false
-				var existingFileData []byte
-
-				if os.Getenv("S3_STORAGE_ENABLED") == "true" && s3Client != nil && s3HealthStatus == health.HealthStatusOK {
-					// Verify and save the CRL to a file
-					crlS3FileName := fmt.Sprintf("%s.crl", config.Configurations.OnlineCrls[i].Name)
-					crlS3FullPath := fmt.Sprintf("%s%s.crl", os.Getenv("AWS_S3_SERVICE_ENDPOINT"), url.PathEscape(config.Configurations.OnlineCrls[i].Name))
-
-					// Check if file already exists
-					s3ObjectOutput, err := s3Client.GetObject(context.TODO(), s3.AWSGetObjectInput(
-						os.Getenv("AWS_S3_BUCKET_NAME"),
-						crlS3FileName,
-					))
-					if err != nil {
-						if strings.Contains(err.Error(), "NoSuchKey") || strings.Contains(err.Error(), "NotFound") {
-							fileExist = false
-							saveNewFile = true
-						} else {
-							logging.LogToConsole(logging.ErrorLevel, logging.ErrorEvent, fmt.Sprintf("[S3] Error checking for existing CRL file in S3 bucket: %v", err))
-							saveNewFile = true
-						}
-					} else {
-						if s3ObjectOutput != nil && s3ObjectOutput.Body != nil {
-							existingFileData, err = io.ReadAll(s3ObjectOutput.Body)
-							if err != nil {
-								logging.LogToConsole(logging.ErrorLevel, logging.ErrorEvent, fmt.Sprintf("[S3] Error reading existing CRL file data from S3 object body: %v", err))
-								saveNewFile = true
-							}
-							s3ObjectOutput.Body.Close()
-						}
-
-						if len(existingFileData) > 0 {
-							fileExist = true
-						}
-					}
-
-					switch fileExist {
-					case true:
-						logging.LogToConsole(logging.DebugLevel, logging.DebugEvent, fmt.Sprintf("[S3] Existing CRL file found at %s, comparing hashes.", crlS3FullPath))
-
-						existingHash := helpers.ComputeHash(existingFileData)
-						newHash := helpers.ComputeHash(rawCRL)
-						hashMaxLength := 25
-						logging.LogToConsole(logging.DebugLevel, logging.DebugEvent, fmt.Sprintf("%-*s %s", hashMaxLength, "[S3] Existing CRL Hash:", existingHash))
-						logging.LogToConsole(logging.DebugLevel, logging.DebugEvent, fmt.Sprintf("%-*s %s", hashMaxLength, "[S3]New CRL Hash:", newHash))
-
-						if existingHash == newHash {
-							logging.LogToConsole(logging.InfoLevel, logging.InfoEvent, fmt.Sprintf("[S3] No changes detected in CRL from %s, skipping save.", crlUrl))
-							// continue // Skip saving if no changes
-							saveNewFile = false
-						} else {
-							logging.LogToConsole(logging.InfoLevel, logging.InfoEvent, fmt.Sprintf("[S3] Changes detected in CRL from %s compared to version on S3, updating file.", crlUrl))
-							logging.LogToConsole(logging.DebugLevel, logging.DebugEvent, fmt.Sprintf("[S3] S3 storage path to save to: %s", crlS3FullPath))
-							saveNewFile = true
-						}
-					case false:
-						logging.LogToConsole(logging.InfoLevel, logging.InfoEvent, fmt.Sprintf("[S3] CRL file %s does not exist in S3 bucket, will proceed to upload new file.", crlS3FileName))
-						saveNewFile = true
-					}
-
-					if saveNewFile {
-						// Save CRL to S3
-						_, err := s3Client.PutObject(context.TODO(), s3.AWSPutObjectInput(
-							os.Getenv("AWS_S3_BUCKET_NAME"),
-							fmt.Sprintf("%s.crl", config.Configurations.OnlineCrls[i].Name),
-							rawCRL,
-						))
-						if err != nil {
-							logging.LogToConsole(logging.ErrorLevel, logging.ErrorEvent, fmt.Sprintf("[S3] Error saving to S3 bucket: %v", err))
-						} else {
-							logging.LogToConsole(logging.DebugLevel, logging.DebugEvent, "[S3] PutObject has not errored.")
-						}
-					}
-				} else if s3Client != nil {
-					logging.LogToConsole(logging.WarningLevel, logging.WarningEvent, "[S3] client not initialized, skipping S3 storage.")
-				}
-			} // if isNextPublishedPassed
-		} else {
-			logging.LogToConsole(logging.WarningLevel, logging.WarningEvent, fmt.Sprintf("CRL from %s is NOT valid.", crlUrl))
-		}
+// readLDAPCredentialsFromVault reads the LDAP bind password, and the bind DN if the secret has one, from a Vault secret
+func readLDAPCredentialsFromVault(secretPath string, bindDN string) (string, string, error) {
+	data, err := vault.GetVaultSecret(secretPath)
+	if err != nil {
+		return "", "", fmt.Errorf("error reading Vault secret %s: %w", secretPath, err)
+	}
+	// KV version 2 nests the secret under "data"
+	if nested, ok := data["data"].(map[string]interface{}); ok {
+		data = nested
+	}
+	if secretBindDN, _ := data["bindDN"].(string); secretBindDN != "" {
+		bindDN = secretBindDN
+	}
+	password, _ := data["password"].(string)
+	if bindDN == "" || password == "" {
+		return "", "", fmt.Errorf("vault secret %s must contain password, and bindDN unless it is configured", secretPath)
+	}
+	return bindDN, password, nil
+} // func readLDAPCredentialsFromVault
 
-		// crlTimeStamps := crlTimeStamps{
-		// 	ThisUpdate:     crl.ThisUpdate,
-		// 	NextUpdate:     crl.NextUpdate,
-		// 	NextCRLPublish: nextPublishTime, // This is a ADCS (Microsoft) specific field and not part of the standard x509.RevocationList
-		// }
+// checkPublishedBase validates a delta CRL against the base CRL currently published in the backend
+func checkPublishedBase(ctx context.Context, backend storage.Backend, delta *crlPublication) error {
+	baseKey := fmt.Sprintf("%s.crl", delta.BaseName)
+	baseData, err := backend.Get(ctx, baseKey)
+	if err != nil {
+		return fmt.Errorf("error reading base CRL %s: %w", baseKey, err)
+	}
+	baseCRL, err := crl.ParseCertificateRevocationList(baseData)
+	if err != nil {
+		return fmt.Errorf("error parsing base CRL %s: %w", baseKey, err)
+	}
+	return crl.ValidateDeltaAgainstBase(delta.Decoded, baseCRL)
+} // func checkPublishedBase
 
-		// log.Printf("crl: %+v\n", crl)
-		// pp.Printf("CRL Published Values: %+v\n", crlTimeStamps)                   // Pretty print the CRL timestamps
-		// pp.Printf("Is NextCRLPublish Zero Value? %v\n", nextPublishTime.IsZero()) // Check and print if NextCRLPublish is zero value
+// commitCRLs serves the CRLs staged in this cycle, and pre-signs the OCSP responses when a served CRL changed
+func commitCRLs(config *cfg.Config) {
+	if !crlCache.Commit() {
+		return
+	}
+	presignOCSPResponses(config)
+} // func commitCRLs
 
-	} // for i, crlUrl := range config.Configuration.OnlineCrls.URL
+// stageForDistribution adds a validated CRL to the cache served by the distribution endpoint
+func stageForDistribution(publication *crlPublication, errChannel chan<- logging.ErrorReport) {
+	name := strings.TrimSuffix(publication.ObjectKey(), ".crl")
+	alertKey := fmt.Sprintf("distribution/%s", name)
+	err := crlCache.Stage(name, publication.Raw, publication.Decoded)
+	if err != nil {
+		errChannel <- logging.ErrorReport{
+			Err:         err,
+			Context:     fmt.Sprintf("Refused to serve CRL %s from %s", publication.Name, publication.Source),
+			Severity:    logging.SeverityCritical,
+			Criticality: logging.CriticalityHigh,
+			Fields:      []logging.Field{logging.CRL(publication.Name), logging.Hash(publication.Hash)},
+			Key:         alertKey,
+		}
+		return
+	}
+	errChannel <- logging.ResolvedReport(alertKey)
 
-	return nil
-} // func processCRLs
+	// Only CRLs accepted for serving are used to answer revocation lookups
+	err = revocationIndex.Update(name, publication.Decoded)
+	if err != nil {
+		logging.LogFields(logging.WarningLevel, logging.WarningEvent, fmt.Sprintf("Error indexing revocations of CRL %s: %v", publication.Name, err), logging.CRL(publication.Name), logging.Err(err))
+	}
+} // func stageForDistribution
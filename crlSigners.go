@@ -0,0 +1,79 @@
+package main
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	crl "trawler/pkg/crl"
+	"trawler/pkg/discovery"
+	logging "trawler/pkg/logging"
+)
+
+var (
+	errUnknownSigner    = errors.New("CRL is signed by an unknown key")
+	errUnexpectedSigner = errors.New("CRL is signed by the certificate of another CA")
+)
+
+// readCACertificates reads all CA certificates in a CA storage path, which are the candidate signers of the CRLs using that path.
+// The configured certificate file of a CRL must be among them.
+func readCACertificates(caStoragePath string, certFileName string) ([]crl.CACertificate, error) {
+	caCertificates, err := discovery.ReadCertificates(caStoragePath)
+	if err != nil {
+		return nil, fmt.Errorf("error reading CA certificates from %s: %w", caStoragePath, err)
+	}
+	if certFileName == "" {
+		return caCertificates, nil
+	}
+	for _, caCertificate := range caCertificates {
+		if caCertificate.FileName == certFileName {
+			return caCertificates, nil
+		}
+	}
+	return nil, fmt.Errorf("certificate file %s not found in %s, or not a certificate", certFileName, caStoragePath)
+} // func readCACertificates
+
+// resolveCRLSigner returns the CA certificate that signed a CRL. A renewed certificate of the configured CA is accepted,
+// while a CRL signed by another CA or by an unknown key is refused with errUnexpectedSigner or errUnknownSigner.
+func resolveCRLSigner(caCertificates []crl.CACertificate, certFileName string, decodedCRL *x509.RevocationList) (*crl.CACertificate, string, error) {
+	signer, relation := crl.FindCRLSigner(caCertificates, certFileName, decodedCRL)
+	switch relation {
+	case crl.SignerUnknown:
+		return nil, relation, fmt.Errorf("%w: issuer %q, Authority Key Identifier %s", errUnknownSigner, decodedCRL.Issuer, keyIDString(decodedCRL.AuthorityKeyId))
+	case crl.SignerUnexpected:
+		return signer, relation, fmt.Errorf("%w: %s (%q) instead of %s", errUnexpectedSigner, signer.FileName, signer.Certificate.Subject, certFileName)
+	}
+	return signer, relation, nil
+} // func resolveCRLSigner
+
+// reportCRLSigner logs a key rollover, and raises a critical alert for a CRL signed by an unexpected or unknown key.
+// The alert is resolved once the CRL is signed by an expected key again.
+func reportCRLSigner(crlName string, certFileName string, signer *crl.CACertificate, relation string, err error, errChannel chan<- logging.ErrorReport) {
+	alertKey := fmt.Sprintf("issuer/%s", crlName)
+	fields := []logging.Field{logging.CRL(crlName)}
+	switch relation {
+	case "":
+		// The CRL was not retrieved or parsed, so its signer is not known
+	case crl.SignerExpected:
+		errChannel <- logging.ResolvedReport(alertKey)
+	case crl.SignerRollover:
+		logging.LogFields(logging.InfoLevel, logging.InfoEvent, fmt.Sprintf("CRL %s is signed by %s, a renewed certificate of the CA in %s (key %s)",
+			crlName, signer.FileName, certFileName, keyIDString(signer.Certificate.SubjectKeyId)), fields...)
+		errChannel <- logging.ResolvedReport(alertKey)
+	default:
+		errChannel <- logging.ErrorReport{
+			Err:         err,
+			Context:     fmt.Sprintf("CRL %s refused, it is not signed by the configured CA", crlName),
+			Severity:    logging.SeverityCritical,
+			Criticality: logging.CriticalityHigh,
+			Fields:      fields,
+			Key:         alertKey,
+		}
+	}
+} // func reportCRLSigner
+
+func keyIDString(keyID []byte) string {
+	if len(keyID) == 0 {
+		return "none"
+	}
+	return fmt.Sprintf("%X", keyID)
+} // func keyIDString
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+	cfg "trawler/pkg/config"
+	crl "trawler/pkg/crl"
+	logging "trawler/pkg/logging"
+)
+
+// Default expiry alert thresholds, used when not set in config
+const (
+	defaultExpiryWarningHours            = 48
+	defaultExpiryCriticalHours           = 12
+	defaultCertificateExpiryWarningDays  = 30
+	defaultCertificateExpiryCriticalDays = 7
+)
+
+// checkExpiry raises alerts for online CRLs and CA certificates that are about to expire
+func checkExpiry(config *cfg.Config, errChannel chan<- logging.ErrorReport) {
+	checkOnlineCRLExpiry(config, errChannel)
+	checkCACertificateExpiry(config, errChannel)
+} // func checkExpiry
+
+// checkOnlineCRLExpiry raises an alert when the NextUpdate of the CRL currently served is within the thresholds.
+// The served CRL is the last valid one, so an issuing CA that stopped publishing is caught before its CRL expires.
+func checkOnlineCRLExpiry(config *cfg.Config, errChannel chan<- logging.ErrorReport) {
+	expiryAlerts := config.Configurations.ExpiryAlerts
+
+	for _, onlineCrl := range config.Configurations.OnlineCrls {
+		entry, exists := crlCache.Get(onlineCrlCacheName(onlineCrl))
+		if !exists {
+			// Not processed successfully yet, which is reported by the processing itself
+			continue
+		}
+
+		warningHours := firstPositive(onlineCrl.ExpiryWarningHours, expiryAlerts.WarningHours, defaultExpiryWarningHours)
+		criticalHours := firstPositive(onlineCrl.ExpiryCriticalHours, expiryAlerts.CriticalHours, defaultExpiryCriticalHours)
+
+		alertKey := fmt.Sprintf("expiry/%s", onlineCrl.Name)
+		remaining := time.Until(entry.NextUpdate)
+		severity, criticality, alert := expirySeverity(remaining, time.Duration(warningHours)*time.Hour, time.Duration(criticalHours)*time.Hour)
+		if !alert {
+			errChannel <- logging.ResolvedReport(alertKey)
+			continue
+		}
+
+		var err error
+		if remaining <= 0 {
+			err = fmt.Errorf("CRL %s expired at %s and no newer valid CRL has been retrieved", onlineCrl.Name, entry.NextUpdate.Format(time.RFC3339))
+		} else {
+			err = fmt.Errorf("CRL %s expires in %s (NextUpdate %s), and no newer valid CRL has been retrieved from %s",
+				onlineCrl.Name, remaining.Round(time.Minute), entry.NextUpdate.Format(time.RFC3339), onlineCrl.URL)
+		}
+		errChannel <- logging.ErrorReport{
+			Err:         err,
+			Context:     fmt.Sprintf("CRL %s is approaching NextUpdate", onlineCrl.Name),
+			Severity:    severity,
+			Criticality: criticality,
+			Fields:      []logging.Field{logging.CRL(onlineCrl.Name), logging.URL(onlineCrl.URL)},
+			Key:         alertKey,
+		}
+	}
+} // func checkOnlineCRLExpiry
+
+// checkCACertificateExpiry raises an alert for CA certificates in the online and offline CA storage paths approaching NotAfter
+func checkCACertificateExpiry(config *cfg.Config, errChannel chan<- logging.ErrorReport) {
+	expiryAlerts := config.Configurations.ExpiryAlerts
+	warningDays := firstPositive(expiryAlerts.CertificateWarningDays, defaultCertificateExpiryWarningDays)
+	criticalDays := firstPositive(expiryAlerts.CertificateCriticalDays, defaultCertificateExpiryCriticalDays)
+
+	for _, caStoragePath := range []string{config.Configurations.Global.OnlineCAStoragePath, config.Configurations.Global.OfflineCAStoragePath} {
+		if caStoragePath == "" {
+			continue
+		}
+		entries, err := os.ReadDir(caStoragePath)
+		if err != nil {
+			logging.LogToConsole(logging.WarningLevel, logging.WarningEvent, fmt.Sprintf("Error reading CA certificates from %s: %v", caStoragePath, err))
+			continue
+		}
+
+		for _, entry := range entries {
+			if !entry.Type().IsRegular() {
+				continue
+			}
+			certFilePath := filepath.Join(caStoragePath, entry.Name())
+			certData, err := os.ReadFile(certFilePath)
+			if err != nil {
+				logging.LogToConsole(logging.WarningLevel, logging.WarningEvent, fmt.Sprintf("Error reading certificate file %s: %v", certFilePath, err))
+				continue
+			}
+			certs, err := crl.ParseCertificates(certData)
+			if err != nil {
+				logging.LogToConsole(logging.DebugLevel, logging.DebugEvent, fmt.Sprintf("Skipping %s for expiry check, not a certificate: %v", certFilePath, err))
+				continue
+			}
+
+			// Each certificate of a bundle is checked on its own, identified by its serial number
+			for _, cert := range certs {
+				alertKey := fmt.Sprintf("certificate-expiry/%s", certFilePath)
+				certName := entry.Name()
+				if len(certs) > 1 {
+					alertKey = fmt.Sprintf("%s/%x", alertKey, cert.SerialNumber)
+					certName = fmt.Sprintf("%s (serial %x)", entry.Name(), cert.SerialNumber)
+				}
+				remaining := time.Until(cert.NotAfter)
+				day := 24 * time.Hour
+				severity, criticality, alert := expirySeverity(remaining, time.Duration(warningDays)*day, time.Duration(criticalDays)*day)
+				if !alert {
+					errChannel <- logging.ResolvedReport(alertKey)
+					continue
+				}
+
+				var expiryErr error
+				if remaining <= 0 {
+					expiryErr = fmt.Errorf("CA certificate %q expired at %s", cert.Subject.String(), cert.NotAfter.Format(time.RFC3339))
+				} else {
+					expiryErr = fmt.Errorf("CA certificate %q expires in %s (NotAfter %s)", cert.Subject.String(), remaining.Round(time.Hour), cert.NotAfter.Format(time.RFC3339))
+				}
+				errChannel <- logging.ErrorReport{
+					Err:         expiryErr,
+					Context:     fmt.Sprintf("CA certificate %s is approaching NotAfter", certName),
+					Severity:    severity,
+					Criticality: criticality,
+					Key:         alertKey,
+				}
+			}
+		}
+	}
+} // func checkCACertificateExpiry
+
+// expirySeverity returns the severity of an alert for the remaining validity, and false if no alert is needed
+func expirySeverity(remaining time.Duration, warning time.Duration, critical time.Duration) (logging.SeverityLevel, logging.CriticalityLevel, bool) {
+	switch {
+	case remaining <= critical:
+		return logging.SeverityCritical, logging.CriticalityCritical, true
+	case remaining <= warning:
+		return logging.SeverityWarning, logging.CriticalityHigh, true
+	default:
+		return "", "", false
+	}
+}
+
+// firstPositive returns the first value that is set, used to apply overrides over defaults
+func firstPositive(values ...int) int {
+	for _, value := range values {
+		if value > 0 {
+			return value
+		}
+	}
+	return 0
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	cfg "trawler/pkg/config"
+	git "trawler/pkg/git"
+	health "trawler/pkg/health"
+	"trawler/pkg/storage"
+	"trawler/pkg/vault"
+)
+
+// Defaults for health checks, used when not set in config
+const (
+	defaultHealthCheckIntervalSeconds = 30
+	defaultWorkerStallMinutes         = 10
+	healthCheckTick                   = 5 * time.Second // How often the registry looks for checks that are due
+)
+
+// Prefixes of component names in the health registry
+const (
+	storageComponentPrefix = "storage/"
+	crlComponentPrefix     = "crl/"
+)
+
+var healthRegistry = health.NewRegistry() // Health checks of all components
+
+// workerHeartbeat is the Unix time of the last progress made by the CRL retrieval worker
+var workerHeartbeat atomic.Int64
+
+// crlSourceErrors holds the error of the last processing of each CRL, nil if it succeeded
+var crlSourceErrors = struct {
+	sync.Mutex
+	errs map[string]error
+}{errs: make(map[string]error)}
+
+// recordWorkerHeartbeat marks that the CRL retrieval worker is making progress
+func recordWorkerHeartbeat() {
+	workerHeartbeat.Store(time.Now().Unix())
+}
+
+// recordCRLSourceResult stores the outcome of processing a CRL for its health check
+func recordCRLSourceResult(crlName string, err error) {
+	crlSourceErrors.Lock()
+	defer crlSourceErrors.Unlock()
+	crlSourceErrors.errs[crlName] = err
+}
+
+// healthCheckInterval returns how often each component is checked
+func healthCheckInterval(config *cfg.Config) time.Duration {
+	intervalSeconds := config.Configurations.Health.CheckIntervalSeconds
+	if intervalSeconds <= 0 {
+		intervalSeconds = defaultHealthCheckIntervalSeconds
+	}
+	return time.Duration(intervalSeconds) * time.Second
+}
+
+// registerHealthChecks registers the checks of the worker loop, storage backends, Vault and Git
+func registerHealthChecks(config *cfg.Config) {
+	interval := healthCheckInterval(config)
+
+	// A wedged worker stops updating CRLs, so it is the only component that makes the pod not ready
+	recordWorkerHeartbeat()
+	healthRegistry.Register("worker", interval, true, func(ctx context.Context) (string, error) {
+		stallMinutes := cfg.Current().Configurations.Health.WorkerStallMinutes
+		if stallMinutes <= 0 {
+			stallMinutes = defaultWorkerStallMinutes
+		}
+		sinceHeartbeat := time.Since(time.Unix(workerHeartbeat.Load(), 0))
+		if sinceHeartbeat > time.Duration(stallMinutes)*time.Minute {
+			return health.HealthStatusUnhealthy, fmt.Errorf("worker has made no progress for %s", sinceHeartbeat.Round(time.Second))
+		}
+		return health.HealthStatusOK, nil
+	})
+
+	registerStorageHealthChecks(config)
+
+	if vaultClient != nil {
+		healthRegistry.Register("vault", interval, false, func(ctx context.Context) (string, error) {
+			if err := vault.HealthCheck(ctx); err != nil {
+				return health.HealthStatusUnhealthy, err
+			}
+			return health.HealthStatusOK, nil
+		})
+	}
+
+	if gitConfig != nil && gitConfig.Enabled {
+		healthRegistry.Register("git", interval, false, func(ctx context.Context) (string, error) {
+			if err := git.HealthCheck(ctx); err != nil {
+				return health.HealthStatusUnhealthy, err
+			}
+			return health.HealthStatusOK, nil
+		})
+	}
+
+	registerCRLHealthChecks(config)
+} // func registerHealthChecks
+
+// registerCRLHealthChecks registers a check for each configured CRL, and removes checks of CRLs no longer in config
+func registerCRLHealthChecks(config *cfg.Config) {
+	interval := healthCheckInterval(config)
+	configured := make(map[string]bool)
+
+	register := func(name string, cacheName string) {
+		configured[crlComponentPrefix+name] = true
+		healthRegistry.Register(crlComponentPrefix+name, interval, false, func(ctx context.Context) (string, error) {
+			crlSourceErrors.Lock()
+			lastErr, processed := crlSourceErrors.errs[name]
+			crlSourceErrors.Unlock()
+			if !processed {
+				return health.HealthStatusUnknown, nil
+			}
+			return checkServedCRL(cacheName, lastErr)
+		})
+	}
+	for _, onlineCrl := range config.Configurations.OnlineCrls {
+		register(onlineCrl.Name, onlineCrlCacheName(onlineCrl))
+	}
+	for _, offlineCrl := range config.Configurations.OfflineCrls {
+		register(offlineCrl.Name, offlineCrl.Name)
+	}
+
+	for _, component := range healthRegistry.Names() {
+		if strings.HasPrefix(component, crlComponentPrefix) && !configured[component] {
+			healthRegistry.Unregister(component)
+		}
+	}
+} // func registerCRLHealthChecks
+
+// registerStorageHealthChecks registers a check for each storage backend, and removes checks of backends no longer in use
+func registerStorageHealthChecks(config *cfg.Config) {
+	interval := healthCheckInterval(config)
+	current := make(map[string]bool)
+
+	for _, backend := range currentStorageBackends() {
+		backend := backend
+		current[storageComponentPrefix+backend.Name()] = true
+		healthRegistry.Register(storageComponentPrefix+backend.Name(), interval, false, func(ctx context.Context) (string, error) {
+			return checkStorageBackend(ctx, backend)
+		})
+	}
+
+	for _, component := range healthRegistry.Names() {
+		if strings.HasPrefix(component, storageComponentPrefix) && !current[component] {
+			healthRegistry.Unregister(component)
+		}
+	}
+} // func registerStorageHealthChecks
+
+// checkServedCRL derives the health of a CRL source from the last processing error and the CRL currently served.
+// A failing source is degraded as long as the last valid CRL has not expired.
+func checkServedCRL(cacheName string, lastErr error) (string, error) {
+	entry, exists := crlCache.Get(cacheName)
+	if !exists {
+		if lastErr != nil {
+			return health.HealthStatusUnhealthy, lastErr
+		}
+		return health.HealthStatusUnhealthy, fmt.Errorf("no valid CRL available")
+	}
+	if time.Now().After(entry.NextUpdate) {
+		return health.HealthStatusUnhealthy, fmt.Errorf("CRL expired at %s", entry.NextUpdate.Format(time.RFC3339))
+	}
+	if lastErr != nil {
+		return health.HealthStatusDegraded, lastErr
+	}
+	return health.HealthStatusOK, nil
+}
+
+// checkStorageBackend reports the health of a single storage backend
+func checkStorageBackend(ctx context.Context, backend storage.Backend) (string, error) {
+	if err := backend.HealthCheck(ctx); err != nil {
+		return health.HealthStatusUnhealthy, err
+	}
+	return health.HealthStatusOK, nil
+}
@@ -1,33 +1,36 @@
 package main
 
 import (
-	"context"
 	"fmt"
+	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"sync"
 	"syscall"
+	"time"
+	"trawler/pkg/api/cdp"
 	api "trawler/pkg/api/health"
+	ocsp "trawler/pkg/api/ocsp"
+	revocationapi "trawler/pkg/api/revocation"
 	cfg "trawler/pkg/config"
+	crl "trawler/pkg/crl"
 	git "trawler/pkg/git"
-	health "trawler/pkg/health"
 	logging "trawler/pkg/logging"
+	"trawler/pkg/metrics"
+	"trawler/pkg/revocation"
 	"trawler/pkg/storage"
-	"trawler/pkg/storage/s3"
 	"trawler/pkg/vault"
 )
 
 var wg sync.WaitGroup              // WaitGroup for goroutines
-var s3Client *s3.Client            // S3 client variable
 var configPath string              // Configuration variables
 var config *cfg.Config             // Global configuration variable
 var vaultClient *vault.VaultClient // Vault client variable
 var gitConfig *git.GitConfig
-
-// Variables for health
-var s3HealthStatus = health.HealthStatusUnknown
-var vaultHealthStatus = health.HealthStatusUnknown
-var gitHealthStatus = health.HealthStatusUnknown
+var crlFetcher *crl.Fetcher                 // HTTP(S) fetcher for online CRLs
+var crlCache = cdp.NewCache()               // Validated CRLs served by the distribution endpoint
+var revocationIndex = revocation.NewIndex() // Revoked serials of the validated CRLs, by issuer
 
 func init() {
 
@@ -50,6 +53,8 @@ func init() {
 		logging.LogToConsole(logging.ErrorLevel, logging.ErrorEvent, fmt.Sprintf("Failed to parse config: %v", err))
 		os.Exit(1)
 	}
+	cfg.SetCurrent(config)
+	logging.Configure(config.Configurations.Global.LogLevel, config.Configurations.Global.OutputFormat)
 
 	// Validate file-structure on local storage
 	//syscall.Umask(0022) // Set umask to ensure created directories are writable
@@ -62,32 +67,14 @@ func init() {
 		logging.LogToConsole(logging.InfoLevel, logging.InfoEvent, "Local storage paths validated successfully.")
 	}
 
-	// Initialize S3 client if S3 storage is enabled and configuration is valid
-	s3Config, err := s3.GetS3Config()
+	// Initialize the fetcher used to retrieve online CRLs
+	initCRLFetcher(config)
 
-	if err != nil && s3Config == nil {
-		logging.LogToConsole(logging.ErrorLevel, logging.ErrorEvent, fmt.Sprintf("S3 configuration validation failed: %v", err))
-	} else if s3Config != nil {
-		logging.LogToConsole(logging.InfoLevel, logging.InfoEvent, "S3 storage enabled and configuration validated successfully.")
-		s3Client, err = s3.AWSCreateS3Client()
-		if err != nil {
-			logging.LogToConsole(logging.ErrorLevel, logging.ErrorEvent, fmt.Sprintf("Failed to create S3 client: %v", err))
-		}
-	}
-	// Validate access to the S3 bucket
-	_, err = s3Client.HeadBucket(context.TODO(), s3.AWSHeadBucketInput(
-		//TODO: consider moving this to a separate health check function that can be called periodically instead of just at startup
-		//TODO: Fix this error: Failed to access S3 bucket: operation error S3: HeadBucket, https response error StatusCode: 301, RequestID: , HostID: , api error MovedPermanently: Moved Permanently
-		os.Getenv("AWS_S3_BUCKET_NAME"),
-	))
-	if err != nil {
-		logging.LogToConsole(logging.ErrorLevel, logging.ErrorEvent, fmt.Sprintf("Failed to access S3 bucket: %v", err))
-		s3HealthStatus = health.HealthStatusUnhealthy
-	} else {
-		logging.LogToConsole(logging.InfoLevel, logging.InfoEvent, "Successfully accessed S3 bucket.")
-		s3HealthStatus = health.HealthStatusOK
+	// Initialize storage backends and validate access to them
+	updateStorageBackends(config)
 
-	}
+	// Apply the retention policy of the CRL archive
+	initCRLArchive(config)
 
 	// Get vault client
 	if os.Getenv("VAULT_ENABLED") == "true" {
@@ -109,18 +96,24 @@ func init() {
 		_, err := git.CloneRepository(config.Configurations.Global.GitStoragePath)
 		if err != nil && err != git.ErrRepoAlreadyExists {
 			logging.LogToConsole(logging.ErrorLevel, logging.ErrorEvent, fmt.Sprintf("Failed to access Git repository: %v", err))
-			gitHealthStatus = health.HealthStatusUnhealthy
 		} else if err != nil && err == git.ErrRepoAlreadyExists {
 			logging.LogToConsole(logging.InfoLevel, logging.InfoEvent, "Git repository already exists locally.")
-			gitHealthStatus = health.HealthStatusOK
 		} else {
 			logging.LogToConsole(logging.InfoLevel, logging.InfoEvent, "Successfully accessed Git repository.")
-			gitHealthStatus = health.HealthStatusOK
 		}
 	} else {
 		logging.LogToConsole(logging.InfoLevel, logging.InfoEvent, "Git storage not enabled, skipping Git repository access validation.")
-		gitHealthStatus = health.HealthStatusUnknown
 	}
+
+	// Track the status of the configured CRLs for the admin API
+	registerCRLStatus(config)
+
+	// Load the delegated signers of the OCSP responder
+	loadOCSPSigners(config)
+
+	// Register and run the health checks of all components, so their status is known before serving traffic
+	registerHealthChecks(config)
+	healthRegistry.CheckNow()
 }
 
 func main() {
@@ -129,7 +122,7 @@ func main() {
 	////////////////////////////////////////////////
 
 	// Define how many processes to wait for
-	wg.Add(3)
+	wg.Add(4)
 
 	// All channels for error-handling and graceful shutdown
 	serverError := make(chan error, 1)                // Channel to capture server errors
@@ -146,16 +139,35 @@ func main() {
 	go func() {
 		defer wg.Done()
 		crlRetrievalWorker(config, errChannel, stopChannel)
+		// The worker is the only sender of errors, so closing lets the error handler deliver pending alerts and stop
+		close(errChannel)
+	}()
+
+	// Start periodic health checks
+	go func() {
+		defer wg.Done()
+		healthRegistry.Start(healthCheckTick, stopChannel)
 	}()
 
 	// Start health API server
 	go func() {
 		defer wg.Done()
-		if err := api.StartHealthServer(8080, stopChannel); err != nil {
+		if err := api.StartHealthServer(8080, stopChannel, healthRegistry, apiRoutes(config)); err != nil {
 			logging.LogToConsole(logging.ErrorLevel, logging.ErrorEvent, fmt.Sprintf("Health server error: %v", err))
 		}
 	}()
 
+	// Start admin API server
+	if config.Configurations.Admin.Enabled {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := startAdminServer(config, stopChannel); err != nil {
+				logging.LogToConsole(logging.ErrorLevel, logging.ErrorEvent, fmt.Sprintf("Admin server error: %v", err))
+			}
+		}()
+	}
+
 	// Setup signal capturing for graceful shutdown
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
@@ -173,3 +185,48 @@ func main() {
 
 	logging.LogToConsole(logging.InfoLevel, logging.InfoEvent, "Shutting down gracefully...")
 }
+
+// apiRoutes returns the endpoints served next to the health checks
+func apiRoutes(config *cfg.Config) map[string]http.Handler {
+	routes := make(map[string]http.Handler)
+
+	// Prometheus metrics, including the health of the components at the time of the scrape
+	metrics.SetComponentStatusFunc(func() map[string]string {
+		statuses := make(map[string]string)
+		for _, component := range healthRegistry.Components() {
+			statuses[component.Name] = component.Status
+		}
+		return statuses
+	})
+	routes["/metrics"] = metrics.Handler()
+
+	// CRLs are public, so the revocation lookup may be served without the authentication of the admin API
+	if config.Configurations.Admin.PublicRevocationLookup {
+		routes[revocationapi.Path] = revocationapi.NewHandler(revocationIndex)
+		logging.LogToConsole(logging.InfoLevel, logging.InfoEvent, fmt.Sprintf("Revocation lookup endpoint enabled at %s", revocationapi.Path))
+	}
+
+	// OCSP clients POST to the path without a trailing slash, so both are routed to the responder
+	if config.Configurations.OCSP.Enabled {
+		pathPrefix := ocspPathPrefix(config)
+		ocspHandler := http.StripPrefix(strings.TrimSuffix(pathPrefix, "/"), ocsp.NewHandler(ocspResponder))
+		routes[pathPrefix] = ocspHandler
+		routes[strings.TrimSuffix(pathPrefix, "/")] = ocspHandler
+		logging.LogToConsole(logging.InfoLevel, logging.InfoEvent, fmt.Sprintf("OCSP responder enabled at %s", pathPrefix))
+	}
+
+	if distribution := config.Configurations.Distribution; distribution.Enabled {
+		pathPrefix := distribution.PathPrefix
+		if pathPrefix == "" {
+			pathPrefix = "/crl/"
+		}
+		if !strings.HasSuffix(pathPrefix, "/") {
+			pathPrefix += "/"
+		}
+		maxCacheAge := time.Duration(distribution.MaxCacheSeconds) * time.Second
+		routes[pathPrefix] = http.StripPrefix(strings.TrimSuffix(pathPrefix, "/"), cdp.NewHandler(crlCache, distribution.ServePEM, maxCacheAge))
+		logging.LogToConsole(logging.InfoLevel, logging.InfoEvent, fmt.Sprintf("CRL distribution endpoint enabled at %s", pathPrefix))
+	}
+
+	return routes
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+	ocsp "trawler/pkg/api/ocsp"
+	cfg "trawler/pkg/config"
+	crl "trawler/pkg/crl"
+	logging "trawler/pkg/logging"
+	"trawler/pkg/vault"
+)
+
+var ocspResponder = ocsp.NewResponder(revocationIndex) // Answers OCSP requests from the revocation index
+
+// loadOCSPSigners (re)loads the delegated OCSP signers of all CAs in config.
+// A CA whose signer cannot be loaded is logged and skipped, and requests about it are answered as unauthorized.
+func loadOCSPSigners(config *cfg.Config) {
+	if !config.Configurations.OCSP.Enabled {
+		return
+	}
+	var signers []*ocsp.Signer
+	for _, responderConfig := range config.Configurations.OCSP.Responders {
+		signer, err := loadOCSPSigner(config, responderConfig)
+		if err != nil {
+			logging.LogFields(logging.ErrorLevel, logging.ErrorEvent, fmt.Sprintf("Error loading OCSP signer for CRL %s: %v", responderConfig.CRL, err), logging.CRL(responderConfig.CRL), logging.Err(err))
+			continue
+		}
+		logging.LogFields(logging.InfoLevel, logging.InfoEvent, fmt.Sprintf("OCSP signer for %s loaded", signer.Issuer.Subject), logging.CRL(responderConfig.CRL))
+		signers = append(signers, signer)
+	}
+	ocspResponder.SetSigners(signers)
+} // func loadOCSPSigners
+
+func loadOCSPSigner(config *cfg.Config, responderConfig cfg.OCSPResponder) (*ocsp.Signer, error) {
+	issuerCertPath, err := caCertificatePath(config, responderConfig.CRL)
+	if err != nil {
+		return nil, err
+	}
+	issuerData, err := os.ReadFile(issuerCertPath)
+	if err != nil {
+		return nil, fmt.Errorf("error reading CA certificate %s: %w", issuerCertPath, err)
+	}
+	issuer, err := crl.ParseCertificate(issuerData)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing CA certificate %s: %w", issuerCertPath, err)
+	}
+
+	var certPEM, keyPEM []byte
+	if responderConfig.VaultPath != "" {
+		certPEM, keyPEM, err = readOCSPSignerFromVault(responderConfig.VaultPath)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		certPEM, err = os.ReadFile(responderConfig.CertFile)
+		if err != nil {
+			return nil, fmt.Errorf("error reading responder certificate: %w", err)
+		}
+		keyPEM, err = os.ReadFile(responderConfig.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("error reading responder key: %w", err)
+		}
+	}
+	return ocsp.NewSigner(responderConfig.CRL, issuer, certPEM, keyPEM)
+} // func loadOCSPSigner
+
+// readOCSPSignerFromVault reads the PEM encoded certificate and private key from a Vault secret
+func readOCSPSignerFromVault(secretPath string) ([]byte, []byte, error) {
+	data, err := vault.GetVaultSecret(secretPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error reading Vault secret %s: %w", secretPath, err)
+	}
+	// KV version 2 nests the secret under "data"
+	if nested, ok := data["data"].(map[string]interface{}); ok {
+		data = nested
+	}
+	certPEM, _ := data["certificate"].(string)
+	keyPEM, _ := data["private_key"].(string)
+	if certPEM == "" || keyPEM == "" {
+		return nil, nil, fmt.Errorf("vault secret %s must contain certificate and private_key", secretPath)
+	}
+	return []byte(certPEM), []byte(keyPEM), nil
+}
+
+// caCertificatePath returns the path of the CA certificate configured for an online or offline CRL
+func caCertificatePath(config *cfg.Config, crlName string) (string, error) {
+	for _, onlineCrl := range config.Configurations.OnlineCrls {
+		if onlineCrl.Name == crlName {
+			return config.Configurations.Global.OnlineCAStoragePath + onlineCrl.CertFileName, nil
+		}
+	}
+	for _, offlineCrl := range config.Configurations.OfflineCrls {
+		if offlineCrl.Name == crlName {
+			return config.Configurations.Global.OfflineCAStoragePath + offlineCrl.CertFileName, nil
+		}
+	}
+	return "", fmt.Errorf("no CRL named %q in config", crlName)
+}
+
+// presignOCSPResponses signs responses for the revoked serials of the current CRLs
+func presignOCSPResponses(config *cfg.Config) {
+	if !config.Configurations.OCSP.Enabled || !config.Configurations.OCSP.Presign {
+		return
+	}
+	start := time.Now()
+	signed, err := ocspResponder.Presign(start)
+	if err != nil {
+		logging.LogFields(logging.ErrorLevel, logging.ErrorEvent, fmt.Sprintf("Error pre-signing OCSP responses: %v", err), logging.Err(err))
+	}
+	logging.LogFields(logging.DebugLevel, logging.DebugEvent, fmt.Sprintf("Pre-signed %d OCSP responses", signed), logging.Duration(time.Since(start)))
+}
+
+// ocspPathPrefix returns the path the OCSP responder is served at, with a trailing slash
+func ocspPathPrefix(config *cfg.Config) string {
+	pathPrefix := config.Configurations.OCSP.PathPrefix
+	if pathPrefix == "" {
+		pathPrefix = "/ocsp/"
+	}
+	if !strings.HasSuffix(pathPrefix, "/") {
+		pathPrefix += "/"
+	}
+	return pathPrefix
+}
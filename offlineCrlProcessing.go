@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+	cfg "trawler/pkg/config"
+	crl "trawler/pkg/crl"
+	logging "trawler/pkg/logging"
+	"trawler/pkg/metrics"
+)
+
+// Default alert thresholds for offline CRLs, used when not set in config
+const (
+	defaultOfflineNoticeDays   = 30
+	defaultOfflineWarningDays  = 14
+	defaultOfflineCriticalDays = 3
+)
+
+func processOfflineCRLs(config *cfg.Config, errChannel chan<- logging.ErrorReport) {
+	// Loop through all offline CRLs defined in the config file
+	for _, offlineCrl := range config.Configurations.OfflineCrls {
+		processOfflineCRL(config, offlineCrl, errChannel)
+	}
+
+	// Serve the CRLs validated in this cycle
+	commitCRLs(config)
+} // func processOfflineCRLs
+
+// processOfflineCRL reads, validates and publishes a single offline CRL
+func processOfflineCRL(config *cfg.Config, offlineCrl cfg.OfflineCrl, errChannel chan<- logging.ErrorReport) {
+	crlFileName := offlineCrl.CrlFileName
+	if crlFileName == "" {
+		crlFileName = offlineCrl.Name + ".crl"
+	}
+	crlFilePath := config.Configurations.Global.OfflineCrlsPath + crlFileName
+	alertKey := fmt.Sprintf("offline/%s", offlineCrl.Name)
+
+	infoMsgCRL := fmt.Sprintf("Processing offline CRL from file: %s", crlFilePath)
+	logging.LogToConsole(logging.InfoLevel, logging.InfoEvent, strings.Repeat("-", len(infoMsgCRL)))
+	logging.LogToConsole(logging.InfoLevel, logging.InfoEvent, infoMsgCRL)
+	logging.LogToConsole(logging.InfoLevel, logging.InfoEvent, strings.Repeat("-", len(infoMsgCRL)))
+
+	// A missing or unreadable root CRL is as severe as an expired one, since nothing can be published for the chain
+	rawCRL, err := os.ReadFile(crlFilePath)
+	if err != nil {
+		recordCRLSourceResult(offlineCrl.Name, err)
+		crlStatus.RecordFetch(offlineCrl.Name, metrics.OutcomeError, err)
+		errChannel <- logging.ErrorReport{
+			Err:         err,
+			Context:     fmt.Sprintf("Error reading offline CRL %s. Path: %s", offlineCrl.Name, crlFilePath),
+			Severity:    logging.SeverityCritical,
+			Criticality: logging.CriticalityHigh,
+			Fields:      []logging.Field{logging.CRL(offlineCrl.Name)},
+			Key:         alertKey,
+		}
+		return
+	}
+	crlStatus.RecordFetch(offlineCrl.Name, metrics.OutcomeSuccess, nil)
+
+	decodedCRL, err := crl.ParseCertificateRevocationList(rawCRL)
+	if err != nil {
+		recordCRLSourceResult(offlineCrl.Name, err)
+		crlStatus.RecordValidation(offlineCrl.Name, metrics.OutcomeError, err)
+		errChannel <- logging.ErrorReport{
+			Err:         err,
+			Context:     fmt.Sprintf("Error parsing offline CRL %s. Path: %s", offlineCrl.Name, crlFilePath),
+			Severity:    logging.SeverityCritical,
+			Criticality: logging.CriticalityHigh,
+			Fields:      []logging.Field{logging.CRL(offlineCrl.Name)},
+			Key:         alertKey,
+		}
+		return
+	}
+	rawCRL = decodedCRL.Raw // Publish DER, also when the file is PEM encoded
+
+	// Raise alerts before reading the CA certificates and validating, so that an expiring CRL escalates
+	// even when its certificate is missing or unreadable, and an expired CRL gets the highest level
+	checkOfflineCRLExpiry(config, offlineCrl.Name, decodedCRL.NextUpdate, errChannel)
+
+	caStoragePath := config.Configurations.Global.OfflineCAStoragePath
+	caCertificates, err := readCACertificates(caStoragePath, offlineCrl.CertFileName)
+	if err != nil {
+		recordCRLSourceResult(offlineCrl.Name, err)
+		crlStatus.RecordValidation(offlineCrl.Name, metrics.OutcomeError, err)
+		errChannel <- logging.ErrorReport{
+			Err:         err,
+			Context:     fmt.Sprintf("Error reading CA certificates for offline CRL %s. Path: %s", offlineCrl.Name, caStoragePath),
+			Severity:    logging.SeverityWarning,
+			Criticality: logging.CriticalityLow,
+			Fields:      []logging.Field{logging.CRL(offlineCrl.Name)},
+			Key:         alertKey,
+		}
+		return
+	}
+
+	signer, relation, err := resolveCRLSigner(caCertificates, offlineCrl.CertFileName, decodedCRL)
+	reportCRLSigner(offlineCrl.Name, offlineCrl.CertFileName, signer, relation, err, errChannel)
+	if err != nil {
+		recordCRLSourceResult(offlineCrl.Name, err)
+		crlStatus.RecordValidation(offlineCrl.Name, metrics.OutcomeError, err)
+		return
+	}
+
+	validateStart := time.Now()
+	valid, _, _, err := crl.IsCRLValid(decodedCRL, signer.Certificate) // Offline CRLs are published manually, so NextPublish is not considered
+	if err != nil {
+		recordCRLSourceResult(offlineCrl.Name, err)
+		metrics.ObserveValidation(offlineCrl.Name, metrics.OutcomeError, time.Since(validateStart))
+		crlStatus.RecordValidation(offlineCrl.Name, metrics.OutcomeError, err)
+		errChannel <- logging.ErrorReport{
+			Err:         err,
+			Context:     fmt.Sprintf("Error validating offline CRL %s", offlineCrl.Name),
+			Severity:    logging.SeverityCritical,
+			Criticality: logging.CriticalityHigh,
+			Fields:      []logging.Field{logging.CRL(offlineCrl.Name)},
+			Key:         alertKey,
+		}
+	} else if valid {
+		recordCRLSourceResult(offlineCrl.Name, nil)
+		errChannel <- logging.ResolvedReport(alertKey)
+		metrics.ObserveValidation(offlineCrl.Name, metrics.OutcomeSuccess, time.Since(validateStart))
+		metrics.ObserveCRL(offlineCrl.Name, decodedCRL, time.Time{}, len(rawCRL))
+		logging.LogFields(logging.InfoLevel, logging.InfoEvent, fmt.Sprintf("Offline CRL %s is valid.", offlineCrl.Name), logging.CRL(offlineCrl.Name))
+		publication := newCRLPublication(offlineCrl.Name, crlFilePath, rawCRL, decodedCRL)
+		crlStatus.RecordValidation(offlineCrl.Name, metrics.OutcomeSuccess, nil)
+		crlStatus.RecordValid(offlineCrl.Name, publication.Hash, decodedCRL, time.Time{})
+
+		ctx, cancel := context.WithTimeout(context.Background(), fetchTimeout(config))
+		defer cancel()
+		if checkCRLAnomalies(ctx, config, publication, offlineCrl.Anomalies, errChannel) {
+			return
+		}
+		stageForDistribution(publication, errChannel)
+		publishCRL(ctx, config, publication, errChannel)
+	} else {
+		recordCRLSourceResult(offlineCrl.Name, errCRLNotValid)
+		metrics.ObserveValidation(offlineCrl.Name, metrics.OutcomeInvalid, time.Since(validateStart))
+		crlStatus.RecordValidation(offlineCrl.Name, metrics.OutcomeInvalid, errCRLNotValid)
+		// An invalid root CRL is never published, so the chain cannot be checked once the last valid CRL expires
+		errChannel <- logging.ErrorReport{
+			Err:         errCRLNotValid,
+			Context:     fmt.Sprintf("Offline CRL %s is NOT valid. Path: %s", offlineCrl.Name, crlFilePath),
+			Severity:    logging.SeverityCritical,
+			Criticality: logging.CriticalityHigh,
+			Fields:      []logging.Field{logging.CRL(offlineCrl.Name)},
+			Key:         alertKey,
+		}
+	}
+} // func processOfflineCRL
+
+// checkOfflineCRLExpiry raises an alert with increasing severity as the NextUpdate of an offline CRL approaches
+func checkOfflineCRLExpiry(config *cfg.Config, crlName string, nextUpdate time.Time, errChannel chan<- logging.ErrorReport) {
+	alertKey := fmt.Sprintf("offline-expiry/%s", crlName)
+	noticeDays := config.Configurations.OfflineCrlAlerts.NoticeDays
+	if noticeDays <= 0 {
+		noticeDays = defaultOfflineNoticeDays
+	}
+	warningDays := config.Configurations.OfflineCrlAlerts.WarningDays
+	if warningDays <= 0 {
+		warningDays = defaultOfflineWarningDays
+	}
+	criticalDays := config.Configurations.OfflineCrlAlerts.CriticalDays
+	if criticalDays <= 0 {
+		criticalDays = defaultOfflineCriticalDays
+	}
+
+	remaining := time.Until(nextUpdate)
+	day := 24 * time.Hour
+
+	var severity logging.SeverityLevel
+	var criticality logging.CriticalityLevel
+	switch {
+	case remaining <= 0:
+		severity, criticality = logging.SeverityCritical, logging.CriticalityCritical
+	case remaining <= time.Duration(criticalDays)*day:
+		severity, criticality = logging.SeverityCritical, logging.CriticalityCritical
+	case remaining <= time.Duration(warningDays)*day:
+		severity, criticality = logging.SeverityWarning, logging.CriticalityHigh
+	case remaining <= time.Duration(noticeDays)*day:
+		severity, criticality = logging.SeverityNormal, logging.CriticalityMedium
+	default:
+		logging.LogFields(logging.DebugLevel, logging.DebugEvent, fmt.Sprintf("Offline CRL %s expires in %s, no alert needed.", crlName, remaining.Round(time.Hour)), logging.CRL(crlName))
+		errChannel <- logging.ResolvedReport(alertKey)
+		return
+	}
+
+	var err error
+	if remaining <= 0 {
+		err = fmt.Errorf("offline CRL %s expired at %s, a new CRL must be signed in a key ceremony", crlName, nextUpdate.Format(time.RFC3339))
+	} else {
+		err = fmt.Errorf("offline CRL %s expires in %s (NextUpdate %s), schedule a key ceremony to sign a new CRL", crlName, remaining.Round(time.Hour), nextUpdate.Format(time.RFC3339))
+	}
+	errChannel <- logging.ErrorReport{
+		Err:         err,
+		Context:     fmt.Sprintf("Offline CRL %s is approaching NextUpdate", crlName),
+		Severity:    severity,
+		Criticality: criticality,
+		Fields:      []logging.Field{logging.CRL(crlName)},
+		Key:         alertKey,
+	}
+} // func checkOfflineCRLExpiry
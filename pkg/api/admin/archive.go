@@ -0,0 +1,70 @@
+package admin
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+	"trawler/pkg/archive"
+)
+
+// ArchiveResponse lists the archived versions of a CRL
+type ArchiveResponse struct {
+	CRL      string            `json:"crl"`
+	Backend  string            `json:"backend,omitempty"` // Backend the versions were read from
+	Versions []archive.Version `json:"versions"`
+}
+
+// HandleArchive serves the CRL version archive at GET /api/v1/crls/{name}/archive.
+// Without parameters the archived versions are listed. With ?at=<RFC 3339 time> the CRL that was current
+// at that time is returned as DER. The archive can be enabled and disabled while running, so enabled is checked on each request.
+func (h *Handler) HandleArchive(reader *archive.Reader, enabled func() bool) {
+	h.mux.HandleFunc("GET /api/v1/crls/{name}/archive", func(w http.ResponseWriter, r *http.Request) {
+		if !enabled() {
+			writeJSON(w, http.StatusNotFound, ErrorResponse{Error: "CRL archive not enabled"})
+			return
+		}
+		name := r.PathValue("name")
+		if _, exists := h.store.Get(name); !exists {
+			writeJSON(w, http.StatusNotFound, ErrorResponse{Error: "unknown CRL"})
+			return
+		}
+
+		atParam := r.URL.Query().Get("at")
+		if atParam == "" {
+			index, backend, err := reader.Versions(r.Context(), name)
+			if err != nil {
+				writeJSON(w, http.StatusBadGateway, ErrorResponse{Error: err.Error()})
+				return
+			}
+			versions := index.Versions
+			if versions == nil {
+				versions = []archive.Version{}
+			}
+			writeJSON(w, http.StatusOK, ArchiveResponse{CRL: name, Backend: backend, Versions: versions})
+			return
+		}
+
+		at, err := time.Parse(time.RFC3339, atParam)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: "at must be an RFC 3339 time"})
+			return
+		}
+		version, data, err := reader.Retrieve(r.Context(), name, at)
+		if errors.Is(err, archive.ErrNoVersion) {
+			writeJSON(w, http.StatusNotFound, ErrorResponse{Error: err.Error()})
+			return
+		} else if err != nil {
+			writeJSON(w, http.StatusBadGateway, ErrorResponse{Error: err.Error()})
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/pkix-crl")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fmt.Sprintf("%s-%s.crl", name, version.CRLNumber)))
+		w.Header().Set("Last-Modified", version.ThisUpdate.UTC().Format(http.TimeFormat))
+		w.Header().Set("X-CRL-Number", version.CRLNumber)
+		w.Header().Set("X-CRL-Hash", version.Hash)
+		w.WriteHeader(http.StatusOK)
+		w.Write(data)
+	})
+}
@@ -0,0 +1,170 @@
+package admin
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+	"trawler/pkg/crl"
+	"trawler/pkg/status"
+)
+
+// ErrRefreshQueueFull is returned by a RefreshFunc when the worker has too many pending refreshes
+var ErrRefreshQueueFull = errors.New("refresh queue is full")
+
+// RefreshFunc asks the CRL retrieval worker to process the named CRLs, or all CRLs if names is empty
+type RefreshFunc func(names []string) error
+
+// CRLListResponse lists the status of all CRLs
+type CRLListResponse struct {
+	Timestamp time.Time          `json:"timestamp"`
+	CRLs      []status.CRLStatus `json:"crls"`
+}
+
+// RefreshResponse confirms that a refresh has been queued
+type RefreshResponse struct {
+	Status string   `json:"status"`
+	CRLs   []string `json:"crls,omitempty"` // Empty when all CRLs are refreshed
+}
+
+// DiffResponse holds the changes of a CRL compared with the version stored before it
+type DiffResponse struct {
+	CRL        string    `json:"crl"`
+	ComputedAt time.Time `json:"computedAt"`
+	Diff       *crl.Diff `json:"diff"`
+}
+
+// ErrorResponse describes why a request failed
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+// Handler serves the admin API
+type Handler struct {
+	mux     *http.ServeMux
+	store   *status.Store
+	refresh RefreshFunc
+	token   string
+}
+
+// NewHandler creates the admin API handler.
+// Requests are accepted with the bearer token, or with a client certificate verified by the TLS server.
+func NewHandler(store *status.Store, refresh RefreshFunc, token string) *Handler {
+	h := &Handler{
+		mux:     http.NewServeMux(),
+		store:   store,
+		refresh: refresh,
+		token:   token,
+	}
+	h.mux.HandleFunc("GET /api/v1/crls", h.listCRLs)
+	h.mux.HandleFunc("GET /api/v1/crls/{name}", h.getCRL)
+	h.mux.HandleFunc("GET /api/v1/crls/{name}/diff", h.getDiff)
+	h.mux.HandleFunc("POST /api/v1/crls/{name}/refresh", h.refreshCRL)
+	h.mux.HandleFunc("POST /api/v1/crls/{name}/quarantine/release", h.releaseQuarantine)
+	h.mux.HandleFunc("POST /api/v1/refresh", h.refreshAll)
+	return h
+}
+
+// Handle registers an additional endpoint behind the same authentication
+func (h *Handler) Handle(pattern string, handler http.Handler) {
+	h.mux.Handle(pattern, handler)
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !h.authorized(r) {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="trawler"`)
+		writeJSON(w, http.StatusUnauthorized, ErrorResponse{Error: "unauthorized"})
+		return
+	}
+	h.mux.ServeHTTP(w, r)
+}
+
+// authorized accepts a verified client certificate or a matching bearer token
+func (h *Handler) authorized(r *http.Request) bool {
+	if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 {
+		return true
+	}
+	if h.token == "" {
+		return false
+	}
+	token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !found {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(token), []byte(h.token)) == 1
+}
+
+func (h *Handler) listCRLs(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, CRLListResponse{Timestamp: time.Now(), CRLs: h.store.List()})
+}
+
+func (h *Handler) getCRL(w http.ResponseWriter, r *http.Request) {
+	crlStatus, exists := h.store.Get(r.PathValue("name"))
+	if !exists {
+		writeJSON(w, http.StatusNotFound, ErrorResponse{Error: "unknown CRL"})
+		return
+	}
+	writeJSON(w, http.StatusOK, crlStatus)
+}
+
+func (h *Handler) getDiff(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if _, exists := h.store.Get(name); !exists {
+		writeJSON(w, http.StatusNotFound, ErrorResponse{Error: "unknown CRL"})
+		return
+	}
+	diff, computedAt, exists := h.store.Diff(name)
+	if !exists {
+		writeJSON(w, http.StatusNotFound, ErrorResponse{Error: "no changes recorded since startup"})
+		return
+	}
+	writeJSON(w, http.StatusOK, DiffResponse{CRL: name, ComputedAt: computedAt, Diff: diff})
+}
+
+func (h *Handler) refreshCRL(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if _, exists := h.store.Get(name); !exists {
+		writeJSON(w, http.StatusNotFound, ErrorResponse{Error: "unknown CRL"})
+		return
+	}
+	h.queueRefresh(w, []string{name})
+}
+
+// releaseQuarantine allows the quarantined version of a CRL to be published, and retrieves it again to do so
+func (h *Handler) releaseQuarantine(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if _, exists := h.store.Get(name); !exists {
+		writeJSON(w, http.StatusNotFound, ErrorResponse{Error: "unknown CRL"})
+		return
+	}
+	if !h.store.ReleaseQuarantine(name) {
+		writeJSON(w, http.StatusConflict, ErrorResponse{Error: "CRL is not quarantined"})
+		return
+	}
+	h.queueRefresh(w, []string{name})
+}
+
+func (h *Handler) refreshAll(w http.ResponseWriter, r *http.Request) {
+	h.queueRefresh(w, nil)
+}
+
+func (h *Handler) queueRefresh(w http.ResponseWriter, names []string) {
+	err := h.refresh(names)
+	if errors.Is(err, ErrRefreshQueueFull) {
+		w.Header().Set("Retry-After", "30")
+		writeJSON(w, http.StatusServiceUnavailable, ErrorResponse{Error: err.Error()})
+		return
+	} else if err != nil {
+		writeJSON(w, http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusAccepted, RefreshResponse{Status: "queued", CRLs: names})
+}
+
+func writeJSON(w http.ResponseWriter, statusCode int, response interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,89 @@
+package admin
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+	"trawler/pkg/logging"
+)
+
+// ServerConfig configures the listener of the admin API
+type ServerConfig struct {
+	Port         int
+	TLSCertFile  string
+	TLSKeyFile   string
+	ClientCAFile string // Enables mTLS, client certificates signed by these CAs are accepted without a token
+}
+
+// StartAdminServer starts the admin API HTTP server
+// It is separate from the health server, so that it can be exposed differently and never to the public
+// It returns when stopChan is closed, shutting down gracefully
+func StartAdminServer(config ServerConfig, stopChan <-chan struct{}, handler http.Handler) error {
+	server := &http.Server{
+		Addr:         fmt.Sprintf(":%d", config.Port),
+		Handler:      handler,
+		ReadTimeout:  5 * time.Second,
+		WriteTimeout: 10 * time.Second,
+		IdleTimeout:  120 * time.Second,
+	}
+
+	useTLS := config.TLSCertFile != "" || config.TLSKeyFile != ""
+	if config.ClientCAFile != "" {
+		if !useTLS {
+			return fmt.Errorf("clientCAFile requires tlsCertFile and tlsKeyFile")
+		}
+		caData, err := os.ReadFile(config.ClientCAFile)
+		if err != nil {
+			return fmt.Errorf("error reading client CA file: %w", err)
+		}
+		clientCAs := x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(caData) {
+			return fmt.Errorf("no certificates found in client CA file %s", config.ClientCAFile)
+		}
+		// Clients without a certificate can still authenticate with the bearer token
+		server.TLSConfig = &tls.Config{
+			ClientAuth: tls.VerifyClientCertIfGiven,
+			ClientCAs:  clientCAs,
+			MinVersion: tls.VersionTLS12,
+		}
+	}
+
+	// Channel to capture server errors
+	serverErr := make(chan error, 1)
+
+	// Start server in a goroutine
+	go func() {
+		logging.LogToConsole(logging.InfoLevel, logging.InfoEvent, fmt.Sprintf("Admin API server listening on port %d (TLS: %t)", config.Port, useTLS))
+		var err error
+		if useTLS {
+			err = server.ListenAndServeTLS(config.TLSCertFile, config.TLSKeyFile)
+		} else {
+			err = server.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
+			serverErr <- fmt.Errorf("admin server error: %w", err)
+		}
+	}()
+
+	// Wait for stop signal
+	select {
+	case err := <-serverErr:
+		return err
+	case <-stopChan:
+		logging.LogToConsole(logging.InfoLevel, logging.InfoEvent, "Shutting down admin API server...")
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		if err := server.Shutdown(ctx); err != nil {
+			return fmt.Errorf("admin server shutdown error: %w", err)
+		}
+
+		logging.LogToConsole(logging.InfoLevel, logging.InfoEvent, "Admin API server stopped")
+		return nil
+	}
+}
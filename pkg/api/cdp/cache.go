@@ -0,0 +1,104 @@
+package cdp
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"sync"
+	"time"
+	"trawler/pkg/crl"
+	"trawler/pkg/helpers"
+)
+
+// Entry is a validated CRL ready to be served
+type Entry struct {
+	Name       string
+	DER        []byte
+	PEM        []byte
+	DERETag    string
+	PEMETag    string
+	ThisUpdate time.Time
+	NextUpdate time.Time
+	decoded    *x509.RevocationList
+}
+
+// Decoded returns the parsed CRL
+func (e *Entry) Decoded() *x509.RevocationList {
+	return e.decoded
+}
+
+// Cache holds the CRLs served by the distribution endpoint.
+// CRLs are staged while a cycle runs and become visible together when the cycle is committed.
+type Cache struct {
+	mu      sync.RWMutex
+	entries map[string]*Entry
+	staged  map[string]*Entry
+}
+
+// NewCache creates an empty CRL cache
+func NewCache() *Cache {
+	return &Cache{
+		entries: make(map[string]*Entry),
+		staged:  make(map[string]*Entry),
+	}
+}
+
+// Stage adds a validated CRL to the next version of the cache.
+// A CRL older than the one currently served is rejected, like in the storage backends.
+func (c *Cache) Stage(name string, rawCRL []byte, decodedCRL *x509.RevocationList) error {
+	pemData := pem.EncodeToMemory(&pem.Block{Type: "X509 CRL", Bytes: rawCRL})
+	entry := &Entry{
+		Name:       name,
+		DER:        rawCRL,
+		PEM:        pemData,
+		DERETag:    fmt.Sprintf("\"%s\"", helpers.ComputeHash(rawCRL)),
+		PEMETag:    fmt.Sprintf("\"%s\"", helpers.ComputeHash(pemData)),
+		ThisUpdate: decodedCRL.ThisUpdate,
+		NextUpdate: decodedCRL.NextUpdate,
+		decoded:    decodedCRL,
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if current, exists := c.entries[name]; exists {
+		err := crl.CheckRollback(current.decoded, decodedCRL)
+		if err != nil {
+			return err
+		}
+	}
+	c.staged[name] = entry
+	return nil
+}
+
+// Commit makes all staged CRLs visible, keeping CRLs that were not processed in this cycle.
+// It reports whether a served CRL was added or changed.
+func (c *Cache) Commit() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.staged) == 0 {
+		return false
+	}
+
+	changed := false
+	entries := make(map[string]*Entry, len(c.entries)+len(c.staged))
+	for name, entry := range c.entries {
+		entries[name] = entry
+	}
+	for name, entry := range c.staged {
+		if current, exists := entries[name]; !exists || current.DERETag != entry.DERETag {
+			changed = true
+		}
+		entries[name] = entry
+	}
+	c.entries = entries
+	c.staged = make(map[string]*Entry)
+	return changed
+}
+
+// Get returns the served CRL with the given name
+func (c *Cache) Get(name string) (*Entry, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	entry, exists := c.entries[name]
+	return entry, exists
+}
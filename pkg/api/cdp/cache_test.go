@@ -0,0 +1,86 @@
+package cdp
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"testing"
+	"time"
+)
+
+// newTestCRLs returns CRLs with increasing CRL numbers, signed by a new CA
+func newTestCRLs(t *testing.T, count int) ([][]byte, []*x509.RevocationList) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "NHN Internal CA"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caCertificate, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var raws [][]byte
+	var decoded []*x509.RevocationList
+	for i := 1; i <= count; i++ {
+		crlDER, err := x509.CreateRevocationList(rand.Reader, &x509.RevocationList{
+			Number:     big.NewInt(int64(i)),
+			ThisUpdate: now.Add(time.Duration(i) * time.Minute),
+			NextUpdate: now.Add(24 * time.Hour),
+		}, caCertificate, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		revocationList, err := x509.ParseRevocationList(crlDER)
+		if err != nil {
+			t.Fatal(err)
+		}
+		raws = append(raws, crlDER)
+		decoded = append(decoded, revocationList)
+	}
+	return raws, decoded
+}
+
+func TestCommitReportsChangedCRLs(t *testing.T) {
+	raws, decoded := newTestCRLs(t, 2)
+	cache := NewCache()
+
+	if cache.Commit() {
+		t.Error("commit without staged CRLs reported a change")
+	}
+	if err := cache.Stage("NHN Internal CA", raws[0], decoded[0]); err != nil {
+		t.Fatal(err)
+	}
+	if !cache.Commit() {
+		t.Error("commit of a new CRL reported no change")
+	}
+	if err := cache.Stage("NHN Internal CA", raws[0], decoded[0]); err != nil {
+		t.Fatal(err)
+	}
+	if cache.Commit() {
+		t.Error("commit of the served CRL reported a change")
+	}
+	if err := cache.Stage("NHN Internal CA", raws[1], decoded[1]); err != nil {
+		t.Fatal(err)
+	}
+	if !cache.Commit() {
+		t.Error("commit of a newer CRL reported no change")
+	}
+}
@@ -0,0 +1,79 @@
+package cdp
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Content types for served CRLs
+const (
+	contentTypeDER = "application/pkix-crl"
+	contentTypePEM = "application/x-pem-file"
+)
+
+// Handler serves CRLs from the cache as "<name>.crl" (DER) and optionally "<name>.pem"
+type Handler struct {
+	cache       *Cache
+	servePEM    bool
+	maxCacheAge time.Duration // Upper bound for Cache-Control max-age, zero for no bound
+}
+
+// NewHandler creates a distribution point handler. Mount it with http.StripPrefix.
+func NewHandler(cache *Cache, servePEM bool, maxCacheAge time.Duration) *Handler {
+	return &Handler{cache: cache, servePEM: servePEM, maxCacheAge: maxCacheAge}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	fileName := strings.TrimPrefix(r.URL.Path, "/")
+	var name string
+	var asPEM bool
+	switch {
+	case strings.HasSuffix(fileName, ".crl"):
+		name = strings.TrimSuffix(fileName, ".crl")
+	case h.servePEM && strings.HasSuffix(fileName, ".pem"):
+		name = strings.TrimSuffix(fileName, ".pem")
+		asPEM = true
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
+	entry, exists := h.cache.Get(name)
+	if !exists {
+		http.NotFound(w, r)
+		return
+	}
+
+	data, etag, contentType := entry.DER, entry.DERETag, contentTypeDER
+	if asPEM {
+		data, etag, contentType = entry.PEM, entry.PEMETag, contentTypePEM
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", h.cacheControl(entry.NextUpdate))
+
+	// ServeContent handles HEAD, If-None-Match, If-Modified-Since and Last-Modified
+	http.ServeContent(w, r, fileName, entry.ThisUpdate, bytes.NewReader(data))
+}
+
+// cacheControl lets clients cache a CRL until its NextUpdate
+func (h *Handler) cacheControl(nextUpdate time.Time) string {
+	maxAge := time.Until(nextUpdate)
+	if maxAge <= 0 {
+		return "no-cache"
+	}
+	if h.maxCacheAge > 0 && maxAge > h.maxCacheAge {
+		maxAge = h.maxCacheAge
+	}
+	return fmt.Sprintf("public, max-age=%d", int64(maxAge.Seconds()))
+}
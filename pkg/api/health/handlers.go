@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"time"
+	"trawler/pkg/health"
 )
 
 // HealthResponse represents the health check response
@@ -27,18 +28,53 @@ func LivenessHandler(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
+// HealthDetailsResponse represents the status of each component
+type HealthDetailsResponse struct {
+	Status     string                   `json:"status"`
+	Timestamp  time.Time                `json:"timestamp"`
+	Service    string                   `json:"service"`
+	Components []health.ComponentStatus `json:"components"`
+}
+
 // ReadinessHandler handles readiness probe requests
-// Returns 200 OK if the application is ready to serve traffic
-func ReadinessHandler(w http.ResponseWriter, r *http.Request) {
-	response := HealthResponse{
-		Status:    "ready",
-		Timestamp: time.Now(),
-		Service:   "trawler",
+// Returns 200 OK if the application is ready to serve traffic, and 503 if a critical component is unhealthy
+func ReadinessHandler(registry *health.Registry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		status := registry.Status()
+		response := HealthResponse{
+			Status:    "ready",
+			Timestamp: time.Now(),
+			Service:   "trawler",
+		}
+		statusCode := http.StatusOK
+		switch {
+		case !health.CheckHealthStatus(status):
+			response.Status = "not ready"
+			statusCode = http.StatusServiceUnavailable
+		case status == health.HealthStatusDegraded:
+			response.Status = "degraded"
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(statusCode)
+		json.NewEncoder(w).Encode(response)
 	}
+}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(response)
+// HealthDetailsHandler returns the status, last check and last error of each component
+func HealthDetailsHandler(registry *health.Registry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		response := HealthDetailsResponse{
+			Status:     registry.Status(),
+			Timestamp:  time.Now(),
+			Service:    "trawler",
+			Components: registry.Components(),
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(response)
+	}
 }
 
 // HealthzHandler is a simple health check endpoint
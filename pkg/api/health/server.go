@@ -5,18 +5,27 @@ import (
 	"fmt"
 	"net/http"
 	"time"
+	"trawler/pkg/health"
 	"trawler/pkg/logging"
 )
 
 // StartHealthServer starts the health check HTTP server
 // It runs in a goroutine and handles graceful shutdown
-func StartHealthServer(port int, stopChan <-chan struct{}) error {
+// Readiness is derived from the component health checks in registry
+// Additional handlers, such as the CRL distribution endpoint, are registered from routes
+func StartHealthServer(port int, stopChan <-chan struct{}, registry *health.Registry, routes map[string]http.Handler) error {
 	mux := http.NewServeMux()
 
 	// Register health check endpoints
 	mux.HandleFunc("/health", HealthHandler)
 	mux.HandleFunc("/live", LivenessHandler)
-	mux.HandleFunc("/ready", ReadinessHandler)
+	mux.HandleFunc("/ready", ReadinessHandler(registry))
+	mux.HandleFunc("/health/details", HealthDetailsHandler(registry))
+
+	// Register additional endpoints
+	for pattern, handler := range routes {
+		mux.Handle(pattern, handler)
+	}
 
 	server := &http.Server{
 		Addr:         fmt.Sprintf(":%d", port),
@@ -0,0 +1,120 @@
+package ocsp
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"trawler/pkg/helpers"
+	"trawler/pkg/logging"
+	"trawler/pkg/revocation"
+
+	xocsp "golang.org/x/crypto/ocsp"
+)
+
+// Content types of RFC 6960
+const (
+	contentTypeRequest  = "application/ocsp-request"
+	contentTypeResponse = "application/ocsp-response"
+)
+
+// maxRequestBytes limits the size of OCSP requests
+const maxRequestBytes = 10 * 1024
+
+// Handler serves OCSP over HTTP as described in RFC 6960, appendix A.
+// GET requests carry the base64 encoded request in the path, POST requests in the body.
+// Mount it with http.StripPrefix.
+type Handler struct {
+	responder *Responder
+}
+
+// NewHandler creates an OCSP handler
+func NewHandler(responder *Responder) *Handler {
+	return &Handler{responder: responder}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var requestDER []byte
+	var err error
+	switch r.Method {
+	case http.MethodGet:
+		requestDER, err = decodeGetRequest(r)
+	case http.MethodPost:
+		if contentType := r.Header.Get("Content-Type"); contentType != "" && contentType != contentTypeRequest {
+			err = fmt.Errorf("unexpected content type %q", contentType)
+			break
+		}
+		requestDER, err = io.ReadAll(io.LimitReader(r.Body, maxRequestBytes+1))
+		if err == nil && len(requestDER) > maxRequestBytes {
+			err = fmt.Errorf("request is larger than %d bytes", maxRequestBytes)
+		}
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err != nil {
+		logging.LogToConsole(logging.DebugLevel, logging.DebugEvent, fmt.Sprintf("Malformed OCSP request: %v", err))
+		writeResponse(w, xocsp.MalformedRequestErrorResponse)
+		return
+	}
+
+	request, err := xocsp.ParseRequest(requestDER)
+	if err != nil {
+		logging.LogToConsole(logging.DebugLevel, logging.DebugEvent, fmt.Sprintf("Malformed OCSP request: %v", err))
+		writeResponse(w, xocsp.MalformedRequestErrorResponse)
+		return
+	}
+
+	now := time.Now()
+	response, err := h.responder.Respond(request, now)
+	if errors.Is(err, ErrUnknownIssuer) {
+		writeResponse(w, xocsp.UnauthorizedErrorResponse)
+		return
+	} else if err != nil {
+		logging.LogFields(logging.ErrorLevel, logging.ErrorEvent, "Error answering OCSP request", logging.Err(err))
+		writeResponse(w, xocsp.InternalErrorErrorResponse)
+		return
+	}
+
+	// Responses to GET requests may be cached by proxies until the CRL they were derived from expires (RFC 5019)
+	if r.Method == http.MethodGet && response.Status != revocation.StatusUnknown && now.Before(response.NextUpdate) {
+		maxAge := int64(response.NextUpdate.Sub(now).Seconds())
+		w.Header().Set("Cache-Control", fmt.Sprintf("max-age=%d, public, no-transform, must-revalidate", maxAge))
+		w.Header().Set("Last-Modified", response.ThisUpdate.UTC().Format(http.TimeFormat))
+		w.Header().Set("Expires", response.NextUpdate.UTC().Format(http.TimeFormat))
+		w.Header().Set("ETag", fmt.Sprintf("\"%s\"", helpers.ComputeHash(response.DER)))
+	} else {
+		w.Header().Set("Cache-Control", "no-cache")
+	}
+	writeResponse(w, response.DER)
+}
+
+// decodeGetRequest decodes the base64 request in the path.
+// The escaped path is used when available, since a request may contain "/" and "+".
+func decodeGetRequest(r *http.Request) ([]byte, error) {
+	encoded := r.URL.EscapedPath()
+	encoded, err := url.PathUnescape(strings.TrimPrefix(encoded, "/"))
+	if err != nil {
+		return nil, err
+	}
+	if encoded == "" {
+		return nil, fmt.Errorf("no request in path")
+	}
+	requestDER, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		// Some clients use the URL safe alphabet
+		requestDER, err = base64.RawURLEncoding.DecodeString(strings.TrimRight(encoded, "="))
+	}
+	return requestDER, err
+}
+
+func writeResponse(w http.ResponseWriter, response []byte) {
+	w.Header().Set("Content-Type", contentTypeResponse)
+	w.WriteHeader(http.StatusOK)
+	w.Write(response)
+}
@@ -0,0 +1,173 @@
+package ocsp
+
+import (
+	"crypto"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+	"trawler/pkg/revocation"
+
+	xocsp "golang.org/x/crypto/ocsp"
+)
+
+// maxCachedResponses bounds the memory used by signed responses
+const maxCachedResponses = 100000
+
+// ErrUnknownIssuer is returned for requests about a CA the responder has no signer for
+var ErrUnknownIssuer = errors.New("no OCSP signer for the issuer")
+
+// Response is a signed OCSP response with the validity of the CRL it was derived from
+type Response struct {
+	DER        []byte
+	Status     string
+	CRL        string
+	ThisUpdate time.Time
+	NextUpdate time.Time // Zero for responses with unknown status
+}
+
+// Responder answers OCSP requests from the revocation index.
+// Responses are cached per serial until a newer CRL of the issuer is indexed, so that each is signed once.
+// Nonces are not supported, as is usual for responders with pre-signed responses (RFC 5019).
+type Responder struct {
+	index   *revocation.Index
+	mu      sync.RWMutex
+	signers []*Signer
+	cacheMu sync.Mutex
+	cache   map[string]*Response
+}
+
+// NewResponder creates a responder without signers
+func NewResponder(index *revocation.Index) *Responder {
+	return &Responder{index: index, cache: make(map[string]*Response)}
+}
+
+// SetSigners replaces the signers of the responder, discarding all cached responses
+func (r *Responder) SetSigners(signers []*Signer) {
+	r.mu.Lock()
+	r.signers = signers
+	r.mu.Unlock()
+
+	r.cacheMu.Lock()
+	r.cache = make(map[string]*Response)
+	r.cacheMu.Unlock()
+}
+
+// signerFor returns the signer of the CA identified in the request
+func (r *Responder) signerFor(request *xocsp.Request) *Signer {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, signer := range r.signers {
+		keyHash, supported := signer.keyHashes[request.HashAlgorithm]
+		if !supported {
+			continue
+		}
+		if string(keyHash) == string(request.IssuerKeyHash) && string(signer.nameHashes[request.HashAlgorithm]) == string(request.IssuerNameHash) {
+			return signer
+		}
+	}
+	return nil
+}
+
+// Respond returns the signed response to a request
+func (r *Responder) Respond(request *xocsp.Request, now time.Time) (*Response, error) {
+	signer := r.signerFor(request)
+	if signer == nil {
+		return nil, ErrUnknownIssuer
+	}
+	return r.respond(signer, request.HashAlgorithm, request.SerialNumber, now)
+}
+
+func (r *Responder) respond(signer *Signer, hash crypto.Hash, serial *big.Int, now time.Time) (*Response, error) {
+	issuer := revocation.Issuer{RawIssuer: signer.Issuer.RawSubject, KeyID: signer.Issuer.SubjectKeyId}
+	result := r.index.Lookup(issuer, serial, now)
+
+	cacheKey := fmt.Sprintf("%s/%d/%x", signer.Name, hash, serial.Bytes())
+	if result.Status != revocation.StatusUnknown {
+		r.cacheMu.Lock()
+		cached, exists := r.cache[cacheKey]
+		r.cacheMu.Unlock()
+		if exists && cached.CRL == result.CRL && cached.Status == result.Status && cached.ThisUpdate.Equal(*result.ThisUpdate) {
+			return cached, nil
+		}
+	}
+
+	template := xocsp.Response{
+		SerialNumber: serial,
+		Certificate:  signer.Certificate,
+		IssuerHash:   hash,
+	}
+	response := &Response{Status: result.Status, CRL: result.CRL}
+	switch result.Status {
+	case revocation.StatusGood:
+		template.Status = xocsp.Good
+	case revocation.StatusRevoked:
+		template.Status = xocsp.Revoked
+		template.RevokedAt = *result.RevocationTime
+		template.RevocationReason = *result.ReasonCode
+	default:
+		// Without a current CRL there is nothing to vouch for, so the response is not cached
+		template.Status = xocsp.Unknown
+		template.ThisUpdate = now
+	}
+	if result.ThisUpdate != nil {
+		template.ThisUpdate = *result.ThisUpdate
+		template.NextUpdate = *result.NextUpdate
+		response.ThisUpdate = *result.ThisUpdate
+		response.NextUpdate = *result.NextUpdate
+	} else {
+		response.ThisUpdate = now
+	}
+
+	der, err := xocsp.CreateResponse(signer.Issuer, signer.Certificate, template, signer.key)
+	if err != nil {
+		return nil, fmt.Errorf("error signing OCSP response: %w", err)
+	}
+	response.DER = der
+
+	if result.Status != revocation.StatusUnknown {
+		r.store(cacheKey, response, now)
+	}
+	return response, nil
+}
+
+// store caches a response, dropping expired responses when the cache is full
+func (r *Responder) store(cacheKey string, response *Response, now time.Time) {
+	r.cacheMu.Lock()
+	defer r.cacheMu.Unlock()
+	if len(r.cache) >= maxCachedResponses {
+		for key, cached := range r.cache {
+			if now.After(cached.NextUpdate) {
+				delete(r.cache, key)
+			}
+		}
+		if len(r.cache) >= maxCachedResponses {
+			r.cache = make(map[string]*Response)
+		}
+	}
+	r.cache[cacheKey] = response
+}
+
+// Presign signs responses for all revoked serials of every CA, so that they are served from the cache.
+// Responses use SHA-1 in the CertID, which is what most clients send. It returns the number of responses signed.
+func (r *Responder) Presign(now time.Time) (int, error) {
+	r.mu.RLock()
+	signers := r.signers
+	r.mu.RUnlock()
+
+	signed := 0
+	var errs []error
+	for _, signer := range signers {
+		issuer := revocation.Issuer{RawIssuer: signer.Issuer.RawSubject, KeyID: signer.Issuer.SubjectKeyId}
+		for _, serial := range r.index.RevokedSerials(issuer, now) {
+			_, err := r.respond(signer, crypto.SHA1, serial, now)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", signer.Name, err))
+				break
+			}
+			signed++
+		}
+	}
+	return signed, errors.Join(errs...)
+}
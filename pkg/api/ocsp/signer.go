@@ -0,0 +1,129 @@
+package ocsp
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"fmt"
+	"trawler/pkg/crl"
+)
+
+// Signer signs OCSP responses for a single CA with a delegated responder certificate
+type Signer struct {
+	Name        string // Name of the CRL the CA publishes
+	Issuer      *x509.Certificate
+	Certificate *x509.Certificate
+	key         crypto.Signer
+	keyHashes   map[crypto.Hash][]byte // Hashes of the issuer public key by hash algorithm
+	nameHashes  map[crypto.Hash][]byte // Hashes of the issuer DN by hash algorithm
+}
+
+// supportedHashes are the hash algorithms accepted in the CertID of requests
+var supportedHashes = []crypto.Hash{crypto.SHA1, crypto.SHA256, crypto.SHA384, crypto.SHA512}
+
+// NewSigner creates a signer from a PEM encoded responder certificate and private key.
+// The responder certificate must be issued by the CA and allowed to sign OCSP responses, unless it is the CA itself.
+func NewSigner(name string, issuer *x509.Certificate, certPEM []byte, keyPEM []byte) (*Signer, error) {
+	certBlock, _ := pem.Decode(certPEM)
+	if certBlock == nil {
+		return nil, fmt.Errorf("no PEM encoded responder certificate found")
+	}
+	cert, err := crl.ParseCertificate(certBlock.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing responder certificate: %w", err)
+	}
+	key, err := parsePrivateKey(keyPEM)
+	if err != nil {
+		return nil, err
+	}
+	if !publicKeysEqual(key.Public(), cert.PublicKey) {
+		return nil, fmt.Errorf("private key does not match the responder certificate")
+	}
+
+	if !cert.Equal(issuer) {
+		err = cert.CheckSignatureFrom(issuer)
+		if err != nil {
+			return nil, fmt.Errorf("responder certificate is not issued by %s: %w", issuer.Subject, err)
+		}
+		if !hasOCSPSigning(cert) {
+			return nil, fmt.Errorf("responder certificate does not have the OCSP signing extended key usage")
+		}
+	}
+
+	var publicKeyInfo struct {
+		Algorithm pkix.AlgorithmIdentifier
+		PublicKey asn1.BitString
+	}
+	if _, err := asn1.Unmarshal(issuer.RawSubjectPublicKeyInfo, &publicKeyInfo); err != nil {
+		return nil, fmt.Errorf("error parsing issuer public key: %w", err)
+	}
+	signer := &Signer{
+		Name:        name,
+		Issuer:      issuer,
+		Certificate: cert,
+		key:         key,
+		keyHashes:   make(map[crypto.Hash][]byte),
+		nameHashes:  make(map[crypto.Hash][]byte),
+	}
+	for _, hash := range supportedHashes {
+		h := hash.New()
+		h.Write(publicKeyInfo.PublicKey.RightAlign())
+		signer.keyHashes[hash] = h.Sum(nil)
+		h.Reset()
+		h.Write(issuer.RawSubject)
+		signer.nameHashes[hash] = h.Sum(nil)
+	}
+	return signer, nil
+}
+
+func hasOCSPSigning(cert *x509.Certificate) bool {
+	for _, usage := range cert.ExtKeyUsage {
+		if usage == x509.ExtKeyUsageOCSPSigning {
+			return true
+		}
+	}
+	return false
+}
+
+// parsePrivateKey parses a PEM encoded PKCS#8, PKCS#1 or SEC 1 private key.
+// Only RSA and ECDSA keys can sign OCSP responses.
+func parsePrivateKey(keyPEM []byte) (crypto.Signer, error) {
+	keyBlock, _ := pem.Decode(keyPEM)
+	if keyBlock == nil {
+		return nil, fmt.Errorf("no PEM encoded private key found")
+	}
+
+	var key interface{}
+	var err error
+	switch keyBlock.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(keyBlock.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(keyBlock.Bytes)
+	default:
+		key, err = x509.ParsePKCS8PrivateKey(keyBlock.Bytes)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error parsing private key: %w", err)
+	}
+
+	switch key := key.(type) {
+	case *rsa.PrivateKey:
+		return key, nil
+	case *ecdsa.PrivateKey:
+		return key, nil
+	default:
+		return nil, fmt.Errorf("unsupported private key type %T", key)
+	}
+}
+
+func publicKeysEqual(a crypto.PublicKey, b crypto.PublicKey) bool {
+	if key, ok := a.(interface{ Equal(crypto.PublicKey) bool }); ok {
+		return key.Equal(b)
+	}
+	return false
+}
@@ -0,0 +1,128 @@
+package revocation
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+	"trawler/pkg/crl"
+	"trawler/pkg/revocation"
+)
+
+// Path the revocation lookup is served at
+const Path = "/api/v1/revocation"
+
+// maxCertificateBytes limits the size of certificates posted for lookup
+const maxCertificateBytes = 64 * 1024
+
+// ErrorResponse describes why a request failed
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+// Handler answers whether a certificate is revoked from the revocation index.
+// GET takes the issuer (DN or hex authority key identifier) and hex serial number as query parameters,
+// POST takes a PEM or DER encoded certificate as body.
+type Handler struct {
+	index *revocation.Index
+}
+
+// NewHandler creates a revocation lookup handler
+func NewHandler(index *revocation.Index) *Handler {
+	return &Handler{index: index}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var issuer revocation.Issuer
+	var serial *big.Int
+	var err error
+
+	switch r.Method {
+	case http.MethodGet:
+		issuer, serial, err = parseQuery(r)
+	case http.MethodPost:
+		issuer, serial, err = parseCertificate(r)
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		writeJSON(w, http.StatusMethodNotAllowed, ErrorResponse{Error: "method not allowed"})
+		return
+	}
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, h.index.Lookup(issuer, serial, time.Now()))
+}
+
+func parseQuery(r *http.Request) (revocation.Issuer, *big.Int, error) {
+	issuerParam := strings.TrimSpace(r.URL.Query().Get("issuer"))
+	serialParam := r.URL.Query().Get("serial")
+	if issuerParam == "" || serialParam == "" {
+		return revocation.Issuer{}, nil, fmt.Errorf("issuer and serial are required")
+	}
+
+	serial, err := parseSerial(serialParam)
+	if err != nil {
+		return revocation.Issuer{}, nil, err
+	}
+
+	// A DN always contains an attribute type and value, anything else is taken as a key identifier
+	if strings.Contains(issuerParam, "=") {
+		return revocation.Issuer{DN: issuerParam}, serial, nil
+	}
+	keyID, err := hex.DecodeString(stripSeparators(issuerParam))
+	if err != nil || len(keyID) == 0 {
+		return revocation.Issuer{}, nil, fmt.Errorf("issuer must be a DN or a hex key identifier")
+	}
+	return revocation.Issuer{KeyID: keyID}, serial, nil
+}
+
+func parseCertificate(r *http.Request) (revocation.Issuer, *big.Int, error) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxCertificateBytes+1))
+	if err != nil {
+		return revocation.Issuer{}, nil, fmt.Errorf("error reading certificate: %w", err)
+	}
+	if len(body) > maxCertificateBytes {
+		return revocation.Issuer{}, nil, fmt.Errorf("certificate is larger than %d bytes", maxCertificateBytes)
+	}
+	if block, _ := pem.Decode(body); block != nil {
+		body = block.Bytes
+	}
+	cert, err := crl.ParseCertificate(body)
+	if err != nil {
+		return revocation.Issuer{}, nil, fmt.Errorf("error parsing certificate: %w", err)
+	}
+	issuer := revocation.Issuer{
+		DN:        cert.Issuer.String(),
+		RawIssuer: cert.RawIssuer,
+		KeyID:     cert.AuthorityKeyId,
+	}
+	return issuer, cert.SerialNumber, nil
+}
+
+// parseSerial parses a hex serial number, as printed by OpenSSL and certutil
+func parseSerial(serialParam string) (*big.Int, error) {
+	serialHex := strings.TrimPrefix(strings.ToLower(stripSeparators(serialParam)), "0x")
+	serial, ok := new(big.Int).SetString(serialHex, 16)
+	if !ok {
+		return nil, fmt.Errorf("serial must be a hex number")
+	}
+	return serial, nil
+}
+
+// stripSeparators removes the colons and spaces commonly used when printing hex values
+func stripSeparators(value string) string {
+	return strings.NewReplacer(":", "", " ", "").Replace(strings.TrimSpace(value))
+}
+
+func writeJSON(w http.ResponseWriter, statusCode int, response interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,267 @@
+package archive
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+	"trawler/pkg/helpers"
+	"trawler/pkg/storage"
+)
+
+// Prefix all archived CRLs are stored below in each backend
+const Prefix = "archive/"
+
+// ErrNoVersion is returned when no archived version matches a request
+var ErrNoVersion = errors.New("no archived CRL version")
+
+// Version describes an archived CRL
+type Version struct {
+	Key            string    `json:"key"`
+	CRLNumber      string    `json:"crlNumber,omitempty"`
+	Hash           string    `json:"hash"`
+	ThisUpdate     time.Time `json:"thisUpdate"`
+	NextUpdate     time.Time `json:"nextUpdate"`
+	RevokedEntries int       `json:"revokedEntries"`
+	Size           int       `json:"size"`
+	ArchivedAt     time.Time `json:"archivedAt"`
+}
+
+// Index lists the archived versions of a CRL, oldest first. It is stored next to the versions.
+type Index struct {
+	Name     string    `json:"name"`
+	Versions []Version `json:"versions"`
+}
+
+// Policy controls how many versions are kept. Zero values keep versions forever.
+// The newest version is always kept.
+type Policy struct {
+	MaxVersions int
+	MaxAge      time.Duration // Versions with a ThisUpdate older than this are removed
+}
+
+// Archiver stores every distinct version of a CRL in a backend
+type Archiver struct {
+	policy   Policy
+	mu       sync.Mutex
+	archived map[string]string // Hash of the last version archived, by backend and CRL name
+}
+
+// NewArchiver creates an archiver applying the given retention policy
+func NewArchiver(policy Policy) *Archiver {
+	return &Archiver{policy: policy, archived: make(map[string]string)}
+}
+
+// SetPolicy replaces the retention policy, applied the next time a version is archived
+func (a *Archiver) SetPolicy(policy Policy) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.policy = policy
+}
+
+// Archive stores the CRL as "archive/<name>/<crlNumber>-<sha256>.crl" unless that version is already archived,
+// then removes the versions outside the retention policy. It reports whether a new version was stored.
+func (a *Archiver) Archive(ctx context.Context, backend storage.Backend, name string, rawCRL []byte, decodedCRL *x509.RevocationList) (bool, error) {
+	hash := helpers.ComputeHash(rawCRL)
+	archivedKey := backend.Name() + "/" + name
+
+	a.mu.Lock()
+	lastHash := a.archived[archivedKey]
+	policy := a.policy
+	a.mu.Unlock()
+	if lastHash == hash {
+		return false, nil
+	}
+
+	index, err := ReadIndex(ctx, backend, name)
+	if err != nil {
+		return false, err
+	}
+	for _, version := range index.Versions {
+		if version.Hash == hash {
+			a.remember(archivedKey, hash)
+			return false, nil
+		}
+	}
+
+	version := Version{
+		Key:            versionKey(name, decodedCRL, hash),
+		Hash:           hash,
+		ThisUpdate:     decodedCRL.ThisUpdate,
+		NextUpdate:     decodedCRL.NextUpdate,
+		RevokedEntries: len(decodedCRL.RevokedCertificateEntries),
+		Size:           len(rawCRL),
+		ArchivedAt:     time.Now(),
+	}
+	if decodedCRL.Number != nil {
+		version.CRLNumber = decodedCRL.Number.String()
+	}
+	err = backend.Put(ctx, version.Key, rawCRL)
+	if err != nil {
+		return false, fmt.Errorf("error storing archived CRL %s: %w", version.Key, err)
+	}
+	index.Versions = append(index.Versions, version)
+	sort.SliceStable(index.Versions, func(i, j int) bool {
+		return index.Versions[i].ThisUpdate.Before(index.Versions[j].ThisUpdate)
+	})
+
+	// The index is written before expired versions are deleted, so it never refers to a missing version
+	kept, expired := applyPolicy(index.Versions, policy, time.Now())
+	index.Versions = kept
+	err = writeIndex(ctx, backend, index)
+	if err != nil {
+		return true, err
+	}
+	a.remember(archivedKey, hash)
+
+	var errs []error
+	for _, version := range expired {
+		err := backend.Delete(ctx, version.Key)
+		if err != nil && !errors.Is(err, storage.ErrNotFound) {
+			errs = append(errs, fmt.Errorf("error removing archived CRL %s: %w", version.Key, err))
+		}
+	}
+	return true, errors.Join(errs...)
+}
+
+func (a *Archiver) remember(archivedKey string, hash string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.archived[archivedKey] = hash
+}
+
+// applyPolicy splits versions, sorted oldest first, into the versions to keep and those to remove
+func applyPolicy(versions []Version, policy Policy, now time.Time) (kept []Version, expired []Version) {
+	for i, version := range versions {
+		newest := i == len(versions)-1
+		tooMany := policy.MaxVersions > 0 && len(versions)-i > policy.MaxVersions
+		tooOld := policy.MaxAge > 0 && now.Sub(version.ThisUpdate) > policy.MaxAge
+		if !newest && (tooMany || tooOld) {
+			expired = append(expired, version)
+		} else {
+			kept = append(kept, version)
+		}
+	}
+	return kept, expired
+}
+
+// versionKey returns the key of an archived version.
+// CRLs without a CRL number are keyed by their ThisUpdate instead.
+func versionKey(name string, decodedCRL *x509.RevocationList, hash string) string {
+	number := decodedCRL.ThisUpdate.UTC().Format("20060102T150405Z")
+	if decodedCRL.Number != nil {
+		number = decodedCRL.Number.String()
+	}
+	return fmt.Sprintf("%s%s/%s-%s.crl", Prefix, name, number, hash)
+}
+
+func indexKey(name string) string {
+	return fmt.Sprintf("%s%s/index.json", Prefix, name)
+}
+
+// ReadIndex returns the archived versions of a CRL in a backend, empty if nothing has been archived yet
+func ReadIndex(ctx context.Context, backend storage.Backend, name string) (*Index, error) {
+	data, err := backend.Get(ctx, indexKey(name))
+	if errors.Is(err, storage.ErrNotFound) {
+		return &Index{Name: name}, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("error reading archive index of %s: %w", name, err)
+	}
+	var index Index
+	err = json.Unmarshal(data, &index)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing archive index of %s: %w", name, err)
+	}
+	index.Name = name
+	return &index, nil
+}
+
+func writeIndex(ctx context.Context, backend storage.Backend, index *Index) error {
+	data, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return err
+	}
+	err = backend.Put(ctx, indexKey(index.Name), data)
+	if err != nil {
+		return fmt.Errorf("error writing archive index of %s: %w", index.Name, err)
+	}
+	return nil
+}
+
+// VersionAt returns the version that was current at the given time, which is the newest version issued at or before it
+func (i *Index) VersionAt(at time.Time) (*Version, error) {
+	var current *Version
+	for n := range i.Versions {
+		version := &i.Versions[n]
+		if version.ThisUpdate.After(at) {
+			continue
+		}
+		if current == nil || version.ThisUpdate.After(current.ThisUpdate) {
+			current = version
+		}
+	}
+	if current == nil {
+		return nil, ErrNoVersion
+	}
+	return current, nil
+}
+
+// Reader retrieves archived CRLs from the first backend that has them
+type Reader struct {
+	backends func() []storage.Backend
+}
+
+// NewReader creates a reader over the backends returned by the given function, in order of preference.
+// The function is called for every read, so the reader follows backends that are replaced on a config refresh.
+func NewReader(backends func() []storage.Backend) *Reader {
+	return &Reader{backends: backends}
+}
+
+// Versions returns the archived versions of a CRL, and the backend they were read from
+func (r *Reader) Versions(ctx context.Context, name string) (*Index, string, error) {
+	var errs []error
+	for _, backend := range r.backends() {
+		index, err := ReadIndex(ctx, backend, name)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", backend.Name(), err))
+			continue
+		}
+		if len(index.Versions) > 0 {
+			return index, backend.Name(), nil
+		}
+	}
+	if len(errs) > 0 {
+		return nil, "", errors.Join(errs...)
+	}
+	return &Index{Name: name}, "", nil
+}
+
+// Retrieve returns the version of a CRL that was current at the given time, and its content
+func (r *Reader) Retrieve(ctx context.Context, name string, at time.Time) (*Version, []byte, error) {
+	var errs []error
+	for _, backend := range r.backends() {
+		index, err := ReadIndex(ctx, backend, name)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", backend.Name(), err))
+			continue
+		}
+		version, err := index.VersionAt(at)
+		if err != nil {
+			continue
+		}
+		data, err := backend.Get(ctx, version.Key)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: error reading %s: %w", backend.Name(), version.Key, err))
+			continue
+		}
+		return version, data, nil
+	}
+	if len(errs) > 0 {
+		return nil, nil, errors.Join(errs...)
+	}
+	return nil, nil, ErrNoVersion
+}
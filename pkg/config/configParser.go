@@ -2,6 +2,7 @@ package config
 
 import (
 	"os"
+	"sync/atomic"
 	"time"
 
 	yaml "gopkg.in/yaml.v3"
@@ -15,6 +16,8 @@ type Config struct {
 			LogLevel             string `yaml:"logLevel"`
 			OutputFormat         string `yaml:"outputFormat"`
 			PollIntervalMinutes  int    `yaml:"pollIntervalMinutes"`
+			MaxConcurrentFetches int    `yaml:"maxConcurrentFetches"`
+			FetchTimeoutSeconds  int    `yaml:"fetchTimeoutSeconds"`
 			DataPath             string `yaml:"dataPath"`
 			OnlineCrlsPath       string `yaml:"onlineCrlsPath"`
 			OfflineCrlsPath      string `yaml:"offlineCrlsPath"`
@@ -25,22 +28,211 @@ type Config struct {
 			GitRepoURL           string `yaml:"gitRepoURL"`
 		} `yaml:"global"`
 		Alarmathan struct {
-			Activate    bool   `yaml:"activate"`
-			WebhookURL  string `yaml:"webhookURL"`
-			ServiceID   string `yaml:"serviceid"`
-			Team        string `yaml:"team"`
-			Cluster     string `yaml:"cluster"`
-			App         string `yaml:"app"`
-			VarselTilOS string `yaml:"varselTilOS"`
+			Activate              bool   `yaml:"activate"`
+			WebhookURL            string `yaml:"webhookURL"`
+			ServiceID             string `yaml:"serviceid"`
+			Team                  string `yaml:"team"`
+			Cluster               string `yaml:"cluster"`
+			App                   string `yaml:"app"`
+			VarselTilOS           string `yaml:"varselTilOS"`
+			RepeatIntervalMinutes int    `yaml:"repeatIntervalMinutes"`
+			ResolveTimeoutMinutes int    `yaml:"resolveTimeoutMinutes"`
+			MaxRetries            int    `yaml:"maxRetries"`
 		} `yaml:"alarmathan"`
-		OnlineCrls []struct {
-			Name         string `yaml:"name"`
-			URL          string `yaml:"url"`
-			CertFileName string `yaml:"certFileName"`
-		} `yaml:"onlineCrls"`
+		Notifiers []Notifier `yaml:"notifiers"`
+		Fetcher   struct {
+			ConnectTimeoutSeconds      int    `yaml:"connectTimeoutSeconds"`
+			ReadTimeoutSeconds         int    `yaml:"readTimeoutSeconds"`
+			MaxRetries                 int    `yaml:"maxRetries"` // Set to -1 to disable retries
+			InitialBackoffMilliseconds int    `yaml:"initialBackoffMilliseconds"`
+			MaxBackoffSeconds          int    `yaml:"maxBackoffSeconds"`
+			MaxBodyBytes               int64  `yaml:"maxBodyBytes"`
+			ProxyURL                   string `yaml:"proxyURL"`
+			CABundlePath               string `yaml:"caBundlePath"`
+			ClientCertPath             string `yaml:"clientCertPath"`
+			ClientKeyPath              string `yaml:"clientKeyPath"`
+			LDAP                       struct {
+				DefaultHost string `yaml:"defaultHost"` // host:port for ldap:/// URLs, which leave the server to the client
+				BindDN      string `yaml:"bindDN"`      // Binds anonymously when empty and not set in the Vault secret, otherwise only over TLS
+				VaultPath   string `yaml:"vaultPath"`   // Secret with "password" and optionally "bindDN"
+			} `yaml:"ldap"`
+		} `yaml:"fetcher"`
+		Scheduling struct {
+			Enabled              bool `yaml:"enabled"`
+			LeadThresholdMinutes int  `yaml:"leadThresholdMinutes"`
+			MinIntervalMinutes   int  `yaml:"minIntervalMinutes"`
+			MaxIntervalMinutes   int  `yaml:"maxIntervalMinutes"`
+			JitterPercent        int  `yaml:"jitterPercent"`
+		} `yaml:"scheduling"`
+		Distribution struct {
+			Enabled         bool   `yaml:"enabled"`
+			PathPrefix      string `yaml:"pathPrefix"`
+			ServePEM        bool   `yaml:"servePem"`
+			MaxCacheSeconds int    `yaml:"maxCacheSeconds"`
+		} `yaml:"distribution"`
+		Health struct {
+			CheckIntervalSeconds int `yaml:"checkIntervalSeconds"`
+			WorkerStallMinutes   int `yaml:"workerStallMinutes"`
+		} `yaml:"health"`
+		Admin struct {
+			Enabled      bool   `yaml:"enabled"`
+			Port         int    `yaml:"port"`
+			Token        string `yaml:"token"` // Bearer token, read from the ADMIN_API_TOKEN environment variable if left empty
+			TLSCertFile  string `yaml:"tlsCertFile"`
+			TLSKeyFile   string `yaml:"tlsKeyFile"`
+			ClientCAFile string `yaml:"clientCAFile"` // Enables mTLS, requires tlsCertFile and tlsKeyFile
+			// Also serve the revocation lookup without authentication on the health server
+			PublicRevocationLookup bool `yaml:"publicRevocationLookup"`
+		} `yaml:"admin"`
+		OCSP struct {
+			Enabled    bool            `yaml:"enabled"`
+			PathPrefix string          `yaml:"pathPrefix"`
+			Presign    bool            `yaml:"presign"` // Sign responses for all revoked serials whenever CRLs are updated
+			Responders []OCSPResponder `yaml:"responders"`
+		} `yaml:"ocsp"`
+		Archive struct {
+			Enabled     bool `yaml:"enabled"`
+			MaxVersions int  `yaml:"maxVersions"` // Versions kept per CRL and backend, 0 for no limit
+			MaxAgeDays  int  `yaml:"maxAgeDays"`  // Days versions are kept after their ThisUpdate, 0 for no limit
+		} `yaml:"archive"`
+		Anomalies AnomalyRules `yaml:"anomalies"` // Default rules, replaced by the rules of a CRL that sets its own
+		Discovery struct {
+			Enabled bool `yaml:"enabled"`
+			// Folder of issued leaf certificates, whose distribution points name the CRLs of their issuing CA
+			LeafCertificatePath string `yaml:"leafCertificatePath"`
+		} `yaml:"discovery"`
+		OnlineCrls []OnlineCrl `yaml:"onlineCrls"`
+		Storage    struct {
+			Backends []StorageBackend `yaml:"backends"`
+		} `yaml:"storage"`
+		ExpiryAlerts struct {
+			WarningHours            int `yaml:"warningHours"`
+			CriticalHours           int `yaml:"criticalHours"`
+			CertificateWarningDays  int `yaml:"certificateWarningDays"`
+			CertificateCriticalDays int `yaml:"certificateCriticalDays"`
+		} `yaml:"expiryAlerts"`
+		OfflineCrls      []OfflineCrl `yaml:"offlineCrls"`
+		OfflineCrlAlerts struct {
+			NoticeDays   int `yaml:"noticeDays"`
+			WarningDays  int `yaml:"warningDays"`
+			CriticalDays int `yaml:"criticalDays"`
+		} `yaml:"offlineCrlAlerts"`
 	} `yaml:"configurations"`
 }
 
+// StorageBackend configures a location that CRLs are published to.
+// Credentials left empty are read from the environment variables of the matching storage type.
+type StorageBackend struct {
+	Name              string `yaml:"name"`
+	Type              string `yaml:"type"`   // local, aws, minio or ibm
+	Path              string `yaml:"path"`   // Base directory for local storage
+	Prefix            string `yaml:"prefix"` // Optional prefix for all object keys
+	Bucket            string `yaml:"bucket"`
+	Endpoint          string `yaml:"endpoint"`
+	AuthEndpoint      string `yaml:"authEndpoint"`
+	Region            string `yaml:"region"`
+	ServiceInstanceID string `yaml:"serviceInstanceID"`
+	UseSSL            bool   `yaml:"useSSL"`
+	AccessKeyID       string `yaml:"accessKeyID"`
+	SecretAccessKey   string `yaml:"secretAccessKey"`
+	Format            string `yaml:"format"` // CRL format: der (default), pem, or both (PEM next to DER as <name>.pem)
+}
+
+// Notifier configures a notification channel that receives alerts next to Alarmathan
+type Notifier struct {
+	Name         string            `yaml:"name"`
+	Type         string            `yaml:"type"`        // webhook, slack, teams or email
+	MinSeverity  string            `yaml:"minSeverity"` // Lowest severity sent: low, normal, warning or critical
+	SendResolved bool              `yaml:"sendResolved"`
+	MaxRetries   int               `yaml:"maxRetries"`
+	Routes       []NotifierRoute   `yaml:"routes"` // Optional, the notifier receives alerts matching any route
+	URL          string            `yaml:"url"`
+	Template     string            `yaml:"template"`    // Go template for the body of generic webhooks
+	ContentType  string            `yaml:"contentType"` // Content type of generic webhooks, defaults to application/json
+	Headers      map[string]string `yaml:"headers"`
+	SMTP         SMTPConfig        `yaml:"smtp"`
+}
+
+// NotifierRoute selects the alerts sent to a notifier. Empty fields match all alerts.
+type NotifierRoute struct {
+	CRLs        []string `yaml:"crls"`        // Names of the CRLs the alert is about
+	KeyPrefixes []string `yaml:"keyPrefixes"` // Prefixes of the alert key, e.g. "process/" or "publish/"
+	MinSeverity string   `yaml:"minSeverity"` // Overrides the severity filter of the notifier for this route
+}
+
+// SMTPConfig configures the mail server used by email notifiers.
+// The password is read from the SMTP_PASSWORD environment variable if left empty.
+type SMTPConfig struct {
+	Host     string   `yaml:"host"`
+	Port     int      `yaml:"port"`
+	Username string   `yaml:"username"`
+	Password string   `yaml:"password"`
+	From     string   `yaml:"from"`
+	To       []string `yaml:"to"`
+	TLS      bool     `yaml:"tls"` // Implicit TLS, e.g. on port 465. Otherwise STARTTLS is used when offered.
+}
+
+// OCSPResponder configures the delegated OCSP signing certificate and key of a CA.
+// They are read from Vault when vaultPath is set, and from certFile and keyFile otherwise.
+type OCSPResponder struct {
+	CRL       string `yaml:"crl"` // Name of the online or offline CRL published by the CA
+	CertFile  string `yaml:"certFile"`
+	KeyFile   string `yaml:"keyFile"`
+	VaultPath string `yaml:"vaultPath"` // Secret with PEM encoded "certificate" and "private_key"
+}
+
+// AnomalyRules detect suspicious changes between the served version of a CRL and a newly retrieved one.
+// Rules left at zero are disabled.
+type AnomalyRules struct {
+	MaxNewRevocations   int     `yaml:"maxNewRevocations"`   // Entries added in a single cycle
+	MaxGrowthPercent    float64 `yaml:"maxGrowthPercent"`    // Growth of the number of entries
+	AlertOnRemovals     bool    `yaml:"alertOnRemovals"`     // Entries removed that were not on hold
+	MaxSizeDropPercent  float64 `yaml:"maxSizeDropPercent"`  // Drop of the number of entries, 100 for an emptied CRL
+	MaxCRLNumberJump    int64   `yaml:"maxCrlNumberJump"`    // Increase of the CRL Number
+	MaxClockSkewMinutes int     `yaml:"maxClockSkewMinutes"` // ThisUpdate later than now
+	Quarantine          bool    `yaml:"quarantine"`          // Hold back CRLs with anomalies until released through the admin API
+}
+
+// OnlineCrl describes a CRL that is retrieved from a distribution point
+type OnlineCrl struct {
+	Name string `yaml:"name"`
+	URL  string `yaml:"url"`
+	// CA certificate (or bundle) the CRL is expected to be signed with. Renewed certificates of the same CA in the CA
+	// storage path are accepted too, matched by key identifier. When empty, any certificate in the path is accepted.
+	CertFileName string `yaml:"certFileName"`
+	BaseCrl      string `yaml:"baseCrl"` // Name of the base CRL, set only for delta CRLs
+	// Further URLs of the same CRL, tried in order when the CRL cannot be retrieved from URL or is not valid
+	Mirrors []string `yaml:"mirrors"`
+	// Retrieve the CRL from URL and all mirrors, warn when they disagree, and use the newest valid CRL
+	MirrorConsistency bool `yaml:"mirrorConsistency"`
+	// Optional overrides of the expiry alert thresholds in expiryAlerts
+	ExpiryWarningHours  int `yaml:"expiryWarningHours"`
+	ExpiryCriticalHours int `yaml:"expiryCriticalHours"`
+	// Optional anomaly rules replacing those in anomalies
+	Anomalies *AnomalyRules `yaml:"anomalies"`
+	// Set for CRLs added by discovery rather than listed in the config file
+	Discovered bool `yaml:"-"`
+}
+
+// IsDelta reports whether the entry describes a delta CRL
+func (c OnlineCrl) IsDelta() bool {
+	return c.BaseCrl != ""
+}
+
+// URLs returns the URL of the CRL followed by its mirrors
+func (c OnlineCrl) URLs() []string {
+	return append([]string{c.URL}, c.Mirrors...)
+}
+
+// OfflineCrl describes a CRL from an offline CA that is published manually and read from local storage
+type OfflineCrl struct {
+	Name         string `yaml:"name"`
+	CertFileName string `yaml:"certFileName"` // Matched like OnlineCrl.CertFileName, among the offline CA certificates
+	CrlFileName  string `yaml:"crlFileName"`  // Optional, defaults to "<name>.crl"
+	// Optional anomaly rules replacing those in anomalies
+	Anomalies *AnomalyRules `yaml:"anomalies"`
+}
+
 func ParseConfig(filePath string) (*Config, error) {
 	data, err := os.ReadFile(filePath)
 	if err != nil {
@@ -56,20 +248,34 @@ func ParseConfig(filePath string) (*Config, error) {
 	return &config, nil
 }
 
-func RefreshConfig(config *Config, filePath string, intervalMinutes int) (bool, error) {
+// current is the configuration in effect. It is replaced as a whole instead of changed in place,
+// since the CRL retrieval worker, the alert handling and the health checks read it from different goroutines.
+var current atomic.Pointer[Config]
+
+// Current returns the configuration in effect
+func Current() *Config {
+	return current.Load()
+}
+
+// SetCurrent makes config the configuration in effect. It must not be changed afterwards.
+func SetCurrent(config *Config) {
+	current.Store(config)
+}
+
+// RefreshConfig returns the config file parsed again if it changed within the last interval, and config otherwise
+func RefreshConfig(config *Config, filePath string, intervalMinutes int) (*Config, bool, error) {
 	configInfo, err := os.Stat(filePath)
 	if err != nil {
-		return false, err
+		return config, false, err
 	}
 
 	if configInfo.ModTime().After(time.Now().Add(-time.Duration(intervalMinutes) * time.Minute)) {
 		newConfig, err := ParseConfig(filePath)
 		if err != nil {
-			return false, err
+			return config, false, err
 		}
-		*config = *newConfig
-		return true, nil
+		return newConfig, true, nil
 	}
 
-	return false, nil
+	return config, false, nil
 }
@@ -0,0 +1,97 @@
+package crl
+
+import (
+	"bytes"
+	"crypto/x509"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+// Anomaly rules, used to identify the rule an anomaly violates
+const (
+	RuleNewRevocations = "maxNewRevocations"
+	RuleGrowth         = "maxGrowthPercent"
+	RuleRemovals       = "alertOnRemovals"
+	RuleSizeDrop       = "maxSizeDropPercent"
+	RuleNumberJump     = "maxCrlNumberJump"
+	RuleClockSkew      = "maxClockSkew"
+)
+
+// AnomalyRules are the limits a new version of a CRL is checked against. Zero values disable a rule.
+type AnomalyRules struct {
+	MaxNewRevocations  int
+	MaxGrowthPercent   float64
+	AlertOnRemovals    bool
+	MaxSizeDropPercent float64
+	MaxCRLNumberJump   int64
+	MaxClockSkew       time.Duration
+}
+
+// Enabled reports whether any rule is enabled
+func (r AnomalyRules) Enabled() bool {
+	return r.MaxNewRevocations > 0 || r.MaxGrowthPercent > 0 || r.AlertOnRemovals || r.MaxSizeDropPercent > 0 ||
+		r.MaxCRLNumberJump > 0 || r.MaxClockSkew > 0
+}
+
+// Anomaly is a violated rule
+type Anomaly struct {
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+func (a Anomaly) String() string {
+	return fmt.Sprintf("%s: %s", a.Rule, a.Message)
+}
+
+// DetectAnomalies checks a CRL against the rules, comparing it with the previous version where one is given.
+// A CRL signed by a different key than the previous version is only checked for clock skew,
+// since its entries and CRL Number are not related to those of the previous version.
+func DetectAnomalies(previous *x509.RevocationList, current *x509.RevocationList, diff *Diff, rules AnomalyRules, now time.Time) []Anomaly {
+	var anomalies []Anomaly
+	if rules.MaxClockSkew > 0 && current.ThisUpdate.After(now.Add(rules.MaxClockSkew)) {
+		anomalies = append(anomalies, Anomaly{RuleClockSkew, fmt.Sprintf("ThisUpdate %s is %s in the future, more than the allowed %s",
+			current.ThisUpdate.UTC().Format(time.RFC3339), current.ThisUpdate.Sub(now).Round(time.Second), rules.MaxClockSkew)})
+	}
+	if previous == nil || diff == nil || !bytes.Equal(previous.RawIssuer, current.RawIssuer) ||
+		(len(previous.AuthorityKeyId) > 0 && len(current.AuthorityKeyId) > 0 && !bytes.Equal(previous.AuthorityKeyId, current.AuthorityKeyId)) {
+		return anomalies
+	}
+
+	// The entries of a delta CRL move to the base CRL when a new base is issued, so their count is not compared
+	newBase := deltaOfNewBase(previous, current)
+	previousEntries := len(previous.RevokedCertificateEntries)
+	currentEntries := len(current.RevokedCertificateEntries)
+
+	if rules.MaxNewRevocations > 0 && len(diff.Added) > rules.MaxNewRevocations {
+		anomalies = append(anomalies, Anomaly{RuleNewRevocations, fmt.Sprintf("%d certificates were revoked, more than the allowed %d",
+			len(diff.Added), rules.MaxNewRevocations)})
+	}
+	if rules.MaxGrowthPercent > 0 && !newBase && previousEntries > 0 && currentEntries > previousEntries {
+		growth := float64(currentEntries-previousEntries) / float64(previousEntries) * 100
+		if growth > rules.MaxGrowthPercent {
+			anomalies = append(anomalies, Anomaly{RuleGrowth, fmt.Sprintf("entries grew by %.1f%% from %d to %d, more than the allowed %.1f%%",
+				growth, previousEntries, currentEntries, rules.MaxGrowthPercent)})
+		}
+	}
+	if rules.AlertOnRemovals {
+		if suspicious := diff.SuspiciousRemovals(); suspicious > 0 {
+			anomalies = append(anomalies, Anomaly{RuleRemovals, fmt.Sprintf("%d revoked certificates were removed", suspicious)})
+		}
+	}
+	if rules.MaxSizeDropPercent > 0 && !newBase && previousEntries > 0 && currentEntries < previousEntries {
+		drop := float64(previousEntries-currentEntries) / float64(previousEntries) * 100
+		if drop > rules.MaxSizeDropPercent {
+			anomalies = append(anomalies, Anomaly{RuleSizeDrop, fmt.Sprintf("entries dropped by %.1f%% from %d to %d, more than the allowed %.1f%%",
+				drop, previousEntries, currentEntries, rules.MaxSizeDropPercent)})
+		}
+	}
+	if rules.MaxCRLNumberJump > 0 && previous.Number != nil && current.Number != nil {
+		jump := new(big.Int).Sub(current.Number, previous.Number)
+		if jump.Cmp(big.NewInt(rules.MaxCRLNumberJump)) > 0 {
+			anomalies = append(anomalies, Anomaly{RuleNumberJump, fmt.Sprintf("CRL Number jumped by %s from %s to %s, more than the allowed %d",
+				jump, previous.Number, current.Number, rules.MaxCRLNumberJump)})
+		}
+	}
+	return anomalies
+}
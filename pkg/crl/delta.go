@@ -0,0 +1,105 @@
+package crl
+
+import (
+	"bytes"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+var (
+	// OIDDeltaCRLIndicator identifies the Delta CRL Indicator extension (RFC 5280, 5.2.4)
+	OIDDeltaCRLIndicator = asn1.ObjectIdentifier{2, 5, 29, 27}
+	// OIDFreshestCRL identifies the Freshest CRL extension pointing to the delta CRL (RFC 5280, 5.2.6)
+	OIDFreshestCRL = asn1.ObjectIdentifier{2, 5, 29, 46}
+)
+
+// ErrDeltaAheadOfBase is returned when a delta CRL refers to a base CRL that is not published yet
+var ErrDeltaAheadOfBase = errors.New("delta CRL is ahead of the published base CRL")
+
+// DeltaCRLBaseNumber returns the BaseCRLNumber of the Delta CRL Indicator, and false if the CRL is not a delta CRL
+func DeltaCRLBaseNumber(crlData *x509.RevocationList) (*big.Int, bool, error) {
+	deltaIndicator := FindExtension(crlData.Extensions, OIDDeltaCRLIndicator)
+	if deltaIndicator == nil {
+		return nil, false, nil
+	}
+	baseCRLNumber := new(big.Int)
+	_, err := asn1.Unmarshal(deltaIndicator.Value, &baseCRLNumber)
+	if err != nil {
+		return nil, true, fmt.Errorf("Error parsing Delta CRL Indicator extension: %v", err)
+	}
+	return baseCRLNumber, true, nil
+}
+
+// ValidateDeltaAgainstBase checks that a delta CRL can be combined with the given base CRL.
+// Per RFC 5280 the base must be from the same issuer, and its CRL Number must be at least the
+// BaseCRLNumber of the delta and lower than the CRL Number of the delta.
+func ValidateDeltaAgainstBase(delta *x509.RevocationList, base *x509.RevocationList) error {
+	baseCRLNumber, isDelta, err := DeltaCRLBaseNumber(delta)
+	if err != nil {
+		return err
+	}
+	if !isDelta {
+		return fmt.Errorf("CRL does not contain a Delta CRL Indicator")
+	}
+	if _, baseIsDelta, _ := DeltaCRLBaseNumber(base); baseIsDelta {
+		return fmt.Errorf("base CRL contains a Delta CRL Indicator")
+	}
+
+	if !bytes.Equal(delta.RawIssuer, base.RawIssuer) {
+		return fmt.Errorf("delta CRL issuer %q does not match base CRL issuer %q", delta.Issuer, base.Issuer)
+	}
+	if len(delta.AuthorityKeyId) > 0 && len(base.AuthorityKeyId) > 0 && !bytes.Equal(delta.AuthorityKeyId, base.AuthorityKeyId) {
+		return fmt.Errorf("delta CRL and base CRL are signed by different keys")
+	}
+
+	if base.Number == nil {
+		return fmt.Errorf("base CRL does not contain a CRL Number")
+	}
+	if base.Number.Cmp(baseCRLNumber) < 0 {
+		return fmt.Errorf("%w: BaseCRLNumber %s, published base CRL Number %s", ErrDeltaAheadOfBase, baseCRLNumber, base.Number)
+	}
+	if delta.Number != nil && delta.Number.Cmp(base.Number) <= 0 {
+		return fmt.Errorf("delta CRL Number %s is not newer than base CRL Number %s", delta.Number, base.Number)
+	}
+	return nil
+}
+
+// distributionPoint is a DistributionPoint of RFC 5280, 4.2.1.13, as used by the Freshest CRL extension
+type distributionPoint struct {
+	DistributionPoint distributionPointName `asn1:"optional,tag:0"`
+	Reason            asn1.BitString        `asn1:"optional,tag:1"`
+	CRLIssuer         asn1.RawValue         `asn1:"optional,tag:2"`
+}
+
+type distributionPointName struct {
+	FullName     []asn1.RawValue  `asn1:"optional,tag:0"`
+	RelativeName pkix.RDNSequence `asn1:"optional,tag:1"`
+}
+
+// FreshestCRLURLs returns the URIs of the Freshest CRL extension, which point to the delta CRLs of the issuer.
+// The Go x509 package parses the CRL Distribution Points extension, but not this one.
+func FreshestCRLURLs(extensions []pkix.Extension) ([]string, error) {
+	freshestCRL := FindExtension(extensions, OIDFreshestCRL)
+	if freshestCRL == nil {
+		return nil, nil
+	}
+	var points []distributionPoint
+	_, err := asn1.Unmarshal(freshestCRL.Value, &points)
+	if err != nil {
+		return nil, fmt.Errorf("Error parsing Freshest CRL extension: %v", err)
+	}
+	var urls []string
+	for _, point := range points {
+		for _, name := range point.DistributionPoint.FullName {
+			// uniformResourceIdentifier [6] IA5String
+			if name.Class == asn1.ClassContextSpecific && name.Tag == 6 {
+				urls = append(urls, string(name.Bytes))
+			}
+		}
+	}
+	return urls, nil
+}
@@ -0,0 +1,325 @@
+package crl
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// CRL reason codes of RFC 5280, section 5.3.1, that entries may be removed for
+const (
+	ReasonCertificateHold = 6
+	ReasonRemoveFromCRL   = 8
+)
+
+// reasonNames are the CRL reason codes of RFC 5280, section 5.3.1
+var reasonNames = map[int]string{
+	0:  "unspecified",
+	1:  "keyCompromise",
+	2:  "cACompromise",
+	3:  "affiliationChanged",
+	4:  "superseded",
+	5:  "cessationOfOperation",
+	6:  "certificateHold",
+	8:  "removeFromCRL",
+	9:  "privilegeWithdrawn",
+	10: "aACompromise",
+}
+
+// ReasonName returns the RFC 5280 name of a CRL reason code
+func ReasonName(reasonCode int) string {
+	if name, exists := reasonNames[reasonCode]; exists {
+		return name
+	}
+	return "unknown"
+}
+
+// extensionNames are the CRL extensions reported by name in diffs
+var extensionNames = map[string]string{
+	"2.5.29.20":                "CRL Number",
+	"2.5.29.27":                "Delta CRL Indicator",
+	"2.5.29.28":                "Issuing Distribution Point",
+	"2.5.29.35":                "Authority Key Identifier",
+	"2.5.29.46":                "Freshest CRL",
+	"1.3.6.1.5.5.7.1.1":        "Authority Information Access",
+	"1.3.6.1.4.1.311.21.1":     "Microsoft CA Version",
+	OIDNextCRLPublish.String(): "Microsoft Next CRL Publish",
+	"1.3.6.1.4.1.311.21.14":    "Microsoft CRL Self CDP",
+}
+
+// volatileExtensions change with every CRL issued, so they are not reported as extension changes
+var volatileExtensions = map[string]bool{
+	"2.5.29.20":                true, // CRL Number, reported separately
+	"2.5.29.27":                true, // Delta CRL Indicator, changes with every base CRL
+	OIDNextCRLPublish.String(): true, // Microsoft Next CRL Publish
+}
+
+// maxDetailEntries limits the entries listed per section by Diff.Details
+const maxDetailEntries = 20
+
+// DiffEntry is a revoked certificate entry that differs between two versions of a CRL
+type DiffEntry struct {
+	Serial         string    `json:"serial"` // Lower case hex
+	RevocationTime time.Time `json:"revocationTime"`
+	ReasonCode     int       `json:"reasonCode"`
+	Reason         string    `json:"reason"`
+	PreviousReason string    `json:"previousReason,omitempty"` // Reason in the previous version, for changed entries
+	Suspicious     bool      `json:"suspicious,omitempty"`     // Removed without being released from hold or moved to a new base CRL
+}
+
+// ExtensionChange is a CRL extension that was added, removed or changed
+type ExtensionChange struct {
+	OID    string `json:"oid"`
+	Name   string `json:"name,omitempty"`
+	Change string `json:"change"` // added, removed or changed
+}
+
+// SignerChange describes a CRL that is signed by a different issuer, key or algorithm than its previous version
+type SignerChange struct {
+	PreviousIssuer    string `json:"previousIssuer"`
+	Issuer            string `json:"issuer"`
+	PreviousKeyID     string `json:"previousKeyId,omitempty"`
+	KeyID             string `json:"keyId,omitempty"`
+	PreviousAlgorithm string `json:"previousAlgorithm"`
+	Algorithm         string `json:"algorithm"`
+}
+
+// Diff is the semantic difference between two versions of a CRL
+type Diff struct {
+	PreviousNumber     string            `json:"previousNumber,omitempty"`
+	Number             string            `json:"number,omitempty"`
+	PreviousThisUpdate time.Time         `json:"previousThisUpdate"`
+	ThisUpdate         time.Time         `json:"thisUpdate"`
+	Added              []DiffEntry       `json:"added"`
+	Removed            []DiffEntry       `json:"removed"`
+	Changed            []DiffEntry       `json:"changed"`
+	Extensions         []ExtensionChange `json:"extensions"`
+	Signer             *SignerChange     `json:"signer,omitempty"`
+}
+
+// DiffCRLs compares a CRL with its previous version.
+// CRL entries are only expected to disappear once the certificate has expired, which the CRL does not tell.
+// Removed entries are therefore reported as suspicious, unless they were on hold, marked removeFromCRL,
+// or the CRL is a delta CRL of a newer base CRL that the entries have moved to.
+func DiffCRLs(previous *x509.RevocationList, current *x509.RevocationList) *Diff {
+	diff := &Diff{
+		PreviousThisUpdate: previous.ThisUpdate,
+		ThisUpdate:         current.ThisUpdate,
+		Added:              []DiffEntry{},
+		Removed:            []DiffEntry{},
+		Changed:            []DiffEntry{},
+		Extensions:         []ExtensionChange{},
+	}
+	if previous.Number != nil {
+		diff.PreviousNumber = previous.Number.String()
+	}
+	if current.Number != nil {
+		diff.Number = current.Number.String()
+	}
+
+	previousEntries := entriesBySerial(previous)
+	currentEntries := entriesBySerial(current)
+	for serial, entry := range currentEntries {
+		previousEntry, listed := previousEntries[serial]
+		if !listed {
+			diff.Added = append(diff.Added, newDiffEntry(serial, entry))
+		} else if entry.ReasonCode != previousEntry.ReasonCode || !entry.RevocationTime.Equal(previousEntry.RevocationTime) {
+			changed := newDiffEntry(serial, entry)
+			changed.PreviousReason = ReasonName(previousEntry.ReasonCode)
+			diff.Changed = append(diff.Changed, changed)
+		}
+	}
+	newBase := deltaOfNewBase(previous, current)
+	for serial, entry := range previousEntries {
+		if _, listed := currentEntries[serial]; listed {
+			continue
+		}
+		removed := newDiffEntry(serial, entry)
+		removed.Suspicious = !newBase && entry.ReasonCode != ReasonCertificateHold && entry.ReasonCode != ReasonRemoveFromCRL
+		diff.Removed = append(diff.Removed, removed)
+	}
+	for _, entries := range [][]DiffEntry{diff.Added, diff.Removed, diff.Changed} {
+		sortEntries(entries)
+	}
+
+	diff.Extensions = diffExtensions(previous, current)
+	if !bytes.Equal(previous.RawIssuer, current.RawIssuer) || !bytes.Equal(previous.AuthorityKeyId, current.AuthorityKeyId) ||
+		previous.SignatureAlgorithm != current.SignatureAlgorithm {
+		diff.Signer = &SignerChange{
+			PreviousIssuer:    previous.Issuer.String(),
+			Issuer:            current.Issuer.String(),
+			PreviousKeyID:     hex.EncodeToString(previous.AuthorityKeyId),
+			KeyID:             hex.EncodeToString(current.AuthorityKeyId),
+			PreviousAlgorithm: previous.SignatureAlgorithm.String(),
+			Algorithm:         current.SignatureAlgorithm.String(),
+		}
+	}
+	return diff
+}
+
+// entriesBySerial returns the entries of a CRL by their serial number in lower case hex
+func entriesBySerial(revocationList *x509.RevocationList) map[string]x509.RevocationListEntry {
+	entries := make(map[string]x509.RevocationListEntry, len(revocationList.RevokedCertificateEntries))
+	for _, entry := range revocationList.RevokedCertificateEntries {
+		entries[entry.SerialNumber.Text(16)] = entry
+	}
+	return entries
+}
+
+func newDiffEntry(serial string, entry x509.RevocationListEntry) DiffEntry {
+	return DiffEntry{
+		Serial:         serial,
+		RevocationTime: entry.RevocationTime,
+		ReasonCode:     entry.ReasonCode,
+		Reason:         ReasonName(entry.ReasonCode),
+	}
+}
+
+// sortEntries orders entries by revocation time, then serial, so that diffs are stable
+func sortEntries(entries []DiffEntry) {
+	sort.Slice(entries, func(i, j int) bool {
+		if !entries[i].RevocationTime.Equal(entries[j].RevocationTime) {
+			return entries[i].RevocationTime.Before(entries[j].RevocationTime)
+		}
+		return entries[i].Serial < entries[j].Serial
+	})
+}
+
+// deltaOfNewBase reports whether the current CRL is a delta CRL of a newer base CRL than the previous one.
+// The entries of the previous delta are then expected to have moved to the new base.
+func deltaOfNewBase(previous *x509.RevocationList, current *x509.RevocationList) bool {
+	previousBase, previousIsDelta, err := DeltaCRLBaseNumber(previous)
+	if err != nil || !previousIsDelta {
+		return false
+	}
+	currentBase, currentIsDelta, err := DeltaCRLBaseNumber(current)
+	if err != nil || !currentIsDelta {
+		return false
+	}
+	return currentBase.Cmp(previousBase) > 0
+}
+
+// diffExtensions compares the CRL extensions by OID, ignoring those that change with every CRL
+func diffExtensions(previous *x509.RevocationList, current *x509.RevocationList) []ExtensionChange {
+	previousValues := make(map[string][]byte, len(previous.Extensions))
+	for _, extension := range previous.Extensions {
+		previousValues[extension.Id.String()] = extension.Value
+	}
+	changes := []ExtensionChange{}
+	seen := make(map[string]bool, len(current.Extensions))
+	for _, extension := range current.Extensions {
+		oid := extension.Id.String()
+		seen[oid] = true
+		if volatileExtensions[oid] {
+			continue
+		}
+		previousValue, existed := previousValues[oid]
+		if !existed {
+			changes = append(changes, newExtensionChange(extension.Id, "added"))
+		} else if !bytes.Equal(previousValue, extension.Value) {
+			changes = append(changes, newExtensionChange(extension.Id, "changed"))
+		}
+	}
+	for _, extension := range previous.Extensions {
+		if !seen[extension.Id.String()] && !volatileExtensions[extension.Id.String()] {
+			changes = append(changes, newExtensionChange(extension.Id, "removed"))
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].OID < changes[j].OID })
+	return changes
+}
+
+func newExtensionChange(oid asn1.ObjectIdentifier, change string) ExtensionChange {
+	return ExtensionChange{OID: oid.String(), Name: extensionNames[oid.String()], Change: change}
+}
+
+// Empty reports whether the versions differ in nothing but their CRL Number, validity and volatile extensions
+func (d *Diff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0 && len(d.Extensions) == 0 && d.Signer == nil
+}
+
+// SuspiciousRemovals returns the number of removed entries that are not explained by the CRL
+func (d *Diff) SuspiciousRemovals() int {
+	suspicious := 0
+	for _, entry := range d.Removed {
+		if entry.Suspicious {
+			suspicious++
+		}
+	}
+	return suspicious
+}
+
+// String returns a one-line summary of the diff, used in logs
+func (d *Diff) String() string {
+	summary := fmt.Sprintf("CRL Number %s -> %s: %d added, %d removed", valueOrNone(d.PreviousNumber), valueOrNone(d.Number), len(d.Added), len(d.Removed))
+	if suspicious := d.SuspiciousRemovals(); suspicious > 0 {
+		summary += fmt.Sprintf(" (%d suspicious)", suspicious)
+	}
+	summary += fmt.Sprintf(", %d changed, %d extension changes", len(d.Changed), len(d.Extensions))
+	if d.Signer != nil {
+		summary += ", signer changed"
+	}
+	return summary
+}
+
+// Details returns a plain text description of the diff, attached to notifications.
+// Long lists of entries are truncated.
+func (d *Diff) Details() string {
+	var details strings.Builder
+	fmt.Fprintf(&details, "%s\n", d.String())
+	fmt.Fprintf(&details, "ThisUpdate %s -> %s\n", d.PreviousThisUpdate.UTC().Format(time.RFC3339), d.ThisUpdate.UTC().Format(time.RFC3339))
+
+	writeEntries := func(title string, entries []DiffEntry) {
+		if len(entries) == 0 {
+			return
+		}
+		fmt.Fprintf(&details, "\n%s:\n", title)
+		for i, entry := range entries {
+			if i == maxDetailEntries {
+				fmt.Fprintf(&details, "  ... and %d more\n", len(entries)-maxDetailEntries)
+				break
+			}
+			fmt.Fprintf(&details, "  %s revoked %s (%s)", entry.Serial, entry.RevocationTime.UTC().Format(time.RFC3339), entry.Reason)
+			if entry.PreviousReason != "" {
+				fmt.Fprintf(&details, ", was %s", entry.PreviousReason)
+			}
+			if entry.Suspicious {
+				details.WriteString(", suspicious")
+			}
+			details.WriteString("\n")
+		}
+	}
+	writeEntries("Added", d.Added)
+	writeEntries("Removed", d.Removed)
+	writeEntries("Changed", d.Changed)
+
+	if len(d.Extensions) > 0 {
+		details.WriteString("\nExtensions:\n")
+		for _, change := range d.Extensions {
+			name := change.OID
+			if change.Name != "" {
+				name = fmt.Sprintf("%s (%s)", change.Name, change.OID)
+			}
+			fmt.Fprintf(&details, "  %s %s\n", name, change.Change)
+		}
+	}
+	if d.Signer != nil {
+		fmt.Fprintf(&details, "\nSigner:\n  issuer %s -> %s\n  key %s -> %s\n  algorithm %s -> %s\n",
+			d.Signer.PreviousIssuer, d.Signer.Issuer,
+			valueOrNone(d.Signer.PreviousKeyID), valueOrNone(d.Signer.KeyID),
+			d.Signer.PreviousAlgorithm, d.Signer.Algorithm)
+	}
+	return details.String()
+}
+
+func valueOrNone(value string) string {
+	if value == "" {
+		return "none"
+	}
+	return value
+}
@@ -0,0 +1,292 @@
+package crl
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+)
+
+// Defaults for the fetcher, used for zero values in FetcherConfig
+const (
+	DefaultConnectTimeout = 10 * time.Second
+	DefaultReadTimeout    = 30 * time.Second
+	DefaultMaxRetries     = 3
+	DefaultInitialBackoff = 500 * time.Millisecond
+	DefaultMaxBackoff     = 30 * time.Second
+	DefaultMaxBodyBytes   = 50 * 1024 * 1024
+)
+
+// ErrBodyTooLarge is returned when a CRL exceeds the configured maximum size
+var ErrBodyTooLarge = errors.New("response body exceeds maximum CRL size")
+
+// StatusError is returned when a distribution point answers with an unexpected HTTP status
+type StatusError struct {
+	URL        string
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected HTTP status %d from %s", e.StatusCode, e.URL)
+}
+
+// FetcherConfig configures how CRLs are retrieved over HTTP(S) and LDAP(S)
+type FetcherConfig struct {
+	ConnectTimeout time.Duration
+	ReadTimeout    time.Duration
+	MaxRetries     int // Retries after the first attempt, negative to disable retries
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	MaxBodyBytes   int64
+	ProxyURL       string // Uses the HTTP(S)_PROXY environment variables when empty
+	CABundlePath   string // PEM bundle trusted in addition to the system roots
+	ClientCertPath string // PEM client certificate for mTLS
+	ClientKeyPath  string // PEM client key for mTLS
+
+	LDAPDefaultHost  string // host:port used for LDAP URLs without a server, e.g. ldap:///CN=...
+	LDAPBindDN       string // Binds anonymously when empty, and only over TLS (ldaps:// or StartTLS) otherwise
+	LDAPBindPassword string
+	LDAPDialer       LDAPDialer // Connects with go-ldap when nil
+}
+
+// FetchResult holds a retrieved CRL and the validators used for conditional requests
+type FetchResult struct {
+	Data         []byte
+	NotModified  bool // The distribution point reported no change since the previous fetch
+	ETag         string
+	LastModified string
+}
+
+// Fetcher retrieves CRLs over HTTP(S) with retries and conditional requests, and from LDAP(S) URLs.
+// It remembers the last response per URL, so unchanged CRLs are not downloaded again.
+type Fetcher struct {
+	client    *http.Client
+	tlsConfig *tls.Config // Used for StartTLS on ldap:// connections
+	ldapDial  LDAPDialer
+	config    FetcherConfig
+
+	mu    sync.Mutex
+	cache map[string]*FetchResult
+}
+
+// NewFetcher creates a Fetcher, applying defaults for all zero values in config
+func NewFetcher(config FetcherConfig) (*Fetcher, error) {
+	if config.ConnectTimeout <= 0 {
+		config.ConnectTimeout = DefaultConnectTimeout
+	}
+	if config.ReadTimeout <= 0 {
+		config.ReadTimeout = DefaultReadTimeout
+	}
+	if config.MaxRetries == 0 {
+		config.MaxRetries = DefaultMaxRetries
+	} else if config.MaxRetries < 0 {
+		config.MaxRetries = 0
+	}
+	if config.InitialBackoff <= 0 {
+		config.InitialBackoff = DefaultInitialBackoff
+	}
+	if config.MaxBackoff <= 0 {
+		config.MaxBackoff = DefaultMaxBackoff
+	}
+	if config.MaxBodyBytes <= 0 {
+		config.MaxBodyBytes = DefaultMaxBodyBytes
+	}
+
+	tlsConfig, err := createTLSConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	proxy := http.ProxyFromEnvironment
+	if config.ProxyURL != "" {
+		proxyURL, err := url.Parse(config.ProxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy URL: %v", err)
+		}
+		proxy = http.ProxyURL(proxyURL)
+	}
+
+	transport := &http.Transport{
+		Proxy: proxy,
+		DialContext: (&net.Dialer{
+			Timeout:   config.ConnectTimeout,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		TLSClientConfig:       tlsConfig,
+		TLSHandshakeTimeout:   config.ConnectTimeout,
+		ResponseHeaderTimeout: config.ReadTimeout,
+		IdleConnTimeout:       90 * time.Second,
+	}
+
+	ldapDial := config.LDAPDialer
+	if ldapDial == nil {
+		ldapDial = dialLDAP(config, tlsConfig)
+	}
+
+	return &Fetcher{
+		client:    &http.Client{Transport: transport},
+		tlsConfig: tlsConfig,
+		ldapDial:  ldapDial,
+		config:    config,
+		cache:     make(map[string]*FetchResult),
+	}, nil
+}
+
+// KeepCache takes over the responses remembered by a previous fetcher, so that replacing a fetcher with new settings
+// does not download every unchanged CRL again
+func (f *Fetcher) KeepCache(previous *Fetcher) {
+	if previous == nil || previous == f {
+		return
+	}
+	previous.mu.Lock()
+	defer previous.mu.Unlock()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for crlURL, result := range previous.cache {
+		if _, exists := f.cache[crlURL]; !exists {
+			f.cache[crlURL] = result
+		}
+	}
+}
+
+func createTLSConfig(config FetcherConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if config.CABundlePath != "" {
+		rootCAs, err := x509.SystemCertPool()
+		if err != nil {
+			rootCAs = x509.NewCertPool()
+		}
+		caBundle, err := os.ReadFile(config.CABundlePath)
+		if err != nil {
+			return nil, fmt.Errorf("error reading CA bundle: %v", err)
+		}
+		if !rootCAs.AppendCertsFromPEM(caBundle) {
+			return nil, fmt.Errorf("no certificates found in CA bundle %s", config.CABundlePath)
+		}
+		tlsConfig.RootCAs = rootCAs
+	}
+
+	if config.ClientCertPath != "" || config.ClientKeyPath != "" {
+		clientCert, err := tls.LoadX509KeyPair(config.ClientCertPath, config.ClientKeyPath)
+		if err != nil {
+			return nil, fmt.Errorf("error loading client certificate: %v", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{clientCert}
+	}
+
+	return tlsConfig, nil
+}
+
+// Fetch retrieves the CRL at crlURL, retrying transient failures with exponential backoff and jitter
+func (f *Fetcher) Fetch(ctx context.Context, crlURL string) (*FetchResult, error) {
+	var lastErr error
+	for attempt := 0; attempt <= f.config.MaxRetries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return nil, fmt.Errorf("%v (last error: %v)", ctx.Err(), lastErr)
+			case <-time.After(f.backoff(attempt)):
+			}
+		}
+
+		result, retry, err := f.fetchOnce(ctx, crlURL)
+		if err == nil {
+			return result, nil
+		}
+		lastErr = err
+		if !retry {
+			break
+		}
+	}
+	return nil, lastErr
+}
+
+// backoff returns the delay before the given attempt, using the upper half of the exponential delay as jitter range
+func (f *Fetcher) backoff(attempt int) time.Duration {
+	delay := f.config.InitialBackoff << (attempt - 1)
+	if delay > f.config.MaxBackoff || delay <= 0 {
+		delay = f.config.MaxBackoff
+	}
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+// fetchOnce performs a single request and reports whether a failure is worth retrying
+func (f *Fetcher) fetchOnce(ctx context.Context, crlURL string) (*FetchResult, bool, error) {
+	if IsLDAPURL(crlURL) {
+		return f.fetchLDAPOnce(ctx, crlURL)
+	}
+
+	attemptCtx, cancel := context.WithTimeout(ctx, f.config.ConnectTimeout+f.config.ReadTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(attemptCtx, http.MethodGet, crlURL, nil)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// Add validators from the previous response to only download changed CRLs
+	f.mu.Lock()
+	cached := f.cache[crlURL]
+	f.mu.Unlock()
+	if cached != nil {
+		if cached.ETag != "" {
+			req.Header.Set("If-None-Match", cached.ETag)
+		}
+		if cached.LastModified != "" {
+			req.Header.Set("If-Modified-Since", cached.LastModified)
+		}
+	}
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, ctx.Err() == nil, err
+	}
+	// Ensure the response body is closed after reading
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified && cached != nil:
+		return &FetchResult{
+			Data:         cached.Data,
+			NotModified:  true,
+			ETag:         cached.ETag,
+			LastModified: cached.LastModified,
+		}, false, nil
+	case resp.StatusCode == http.StatusOK:
+		// Continue below
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError:
+		return nil, true, &StatusError{URL: crlURL, StatusCode: resp.StatusCode}
+	default:
+		return nil, false, &StatusError{URL: crlURL, StatusCode: resp.StatusCode}
+	}
+
+	// Read one byte past the limit to detect oversized responses
+	data, err := io.ReadAll(io.LimitReader(resp.Body, f.config.MaxBodyBytes+1))
+	if err != nil {
+		return nil, ctx.Err() == nil, err
+	}
+	if int64(len(data)) > f.config.MaxBodyBytes {
+		return nil, false, ErrBodyTooLarge
+	}
+
+	result := &FetchResult{
+		Data:         data,
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+	}
+	f.mu.Lock()
+	f.cache[crlURL] = result
+	f.mu.Unlock()
+
+	return result, false, nil
+}
@@ -0,0 +1,45 @@
+package crl
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestKeepCacheAvoidsDownloadAfterReplacingFetcher(t *testing.T) {
+	downloads := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		downloads++
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte("crl"))
+	}))
+	defer server.Close()
+
+	previous, err := NewFetcher(FetcherConfig{MaxRetries: -1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := previous.Fetch(context.Background(), server.URL); err != nil {
+		t.Fatal(err)
+	}
+
+	// New settings, but the CRL has not changed since the previous fetcher retrieved it
+	fetcher, err := NewFetcher(FetcherConfig{MaxRetries: -1, ReadTimeout: time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fetcher.KeepCache(previous)
+	result, err := fetcher.Fetch(context.Background(), server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.NotModified || string(result.Data) != "crl" || downloads != 1 {
+		t.Errorf("got NotModified %v, data %q after %d downloads, want the cached CRL after 1 download", result.NotModified, result.Data, downloads)
+	}
+}
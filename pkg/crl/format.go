@@ -0,0 +1,127 @@
+package crl
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/pem"
+	"errors"
+	"fmt"
+)
+
+// PEM block types of CRLs and certificates
+const (
+	PEMTypeCRL         = "X509 CRL"
+	PEMTypeCertificate = "CERTIFICATE"
+	PEMTypePKCS7       = "PKCS7"
+)
+
+var oidSignedData = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+
+// pkcs7ContentInfo is the outer structure of a PKCS#7 message (RFC 2315)
+type pkcs7ContentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"explicit,optional,tag:0"`
+}
+
+// pkcs7SignedData is the content of a PKCS#7 certificate bundle, of which only the certificates are used
+type pkcs7SignedData struct {
+	Version          int
+	DigestAlgorithms asn1.RawValue
+	ContentInfo      asn1.RawValue
+	Certificates     asn1.RawValue `asn1:"optional,tag:0"`
+	CRLs             asn1.RawValue `asn1:"optional,tag:1"`
+	SignerInfos      asn1.RawValue
+}
+
+// IsPEM reports whether data starts with a PEM block, ignoring leading whitespace
+func IsPEM(data []byte) bool {
+	return bytes.HasPrefix(bytes.TrimSpace(data), []byte("-----BEGIN "))
+}
+
+// EncodePEM encodes a DER CRL as a PEM block
+func EncodePEM(der []byte) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: PEMTypeCRL, Bytes: der})
+}
+
+// DecodePEM returns the DER of the first CRL in PEM encoded data, or the data itself if it is not PEM encoded
+func DecodePEM(data []byte) ([]byte, error) {
+	if !IsPEM(data) {
+		return data, nil
+	}
+	rest := data
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			return nil, errors.New("no X509 CRL block found in PEM data")
+		}
+		if block.Type == PEMTypeCRL || block.Type == "CRL" {
+			return block.Bytes, nil
+		}
+	}
+}
+
+// ParseCertificates parses the certificates in DER, PEM (one or more blocks) or PKCS#7 (.p7b/.p7c, DER or PEM) form
+func ParseCertificates(data []byte) ([]*x509.Certificate, error) {
+	if !IsPEM(data) {
+		certificates, err := x509.ParseCertificates(data)
+		if err == nil && len(certificates) > 0 {
+			return certificates, nil
+		}
+		if pkcs7Certificates, pkcs7Err := parsePKCS7Certificates(data); pkcs7Err == nil {
+			return pkcs7Certificates, nil
+		}
+		if err == nil {
+			err = errors.New("no certificates found")
+		}
+		return nil, err
+	}
+
+	var certificates []*x509.Certificate
+	rest := data
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		switch block.Type {
+		case PEMTypeCertificate:
+			certificate, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return nil, err
+			}
+			certificates = append(certificates, certificate)
+		case PEMTypePKCS7:
+			pkcs7Certificates, err := parsePKCS7Certificates(block.Bytes)
+			if err != nil {
+				return nil, err
+			}
+			certificates = append(certificates, pkcs7Certificates...)
+		}
+	}
+	if len(certificates) == 0 {
+		return nil, errors.New("no certificates found in PEM data")
+	}
+	return certificates, nil
+}
+
+// parsePKCS7Certificates returns the certificates of a DER encoded PKCS#7 SignedData message
+func parsePKCS7Certificates(data []byte) ([]*x509.Certificate, error) {
+	var contentInfo pkcs7ContentInfo
+	if _, err := asn1.Unmarshal(data, &contentInfo); err != nil {
+		return nil, fmt.Errorf("not a PKCS#7 message: %w", err)
+	}
+	if !contentInfo.ContentType.Equal(oidSignedData) {
+		return nil, fmt.Errorf("unsupported PKCS#7 content type %s", contentInfo.ContentType)
+	}
+	var signedData pkcs7SignedData
+	if _, err := asn1.Unmarshal(contentInfo.Content.Bytes, &signedData); err != nil {
+		return nil, fmt.Errorf("invalid PKCS#7 signed data: %w", err)
+	}
+	if len(signedData.Certificates.Bytes) == 0 {
+		return nil, errors.New("no certificates found in PKCS#7 message")
+	}
+	return x509.ParseCertificates(signedData.Certificates.Bytes)
+}
@@ -0,0 +1,260 @@
+package crl
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// Attributes CRLs are published in on CRL distribution point objects, e.g. by Active Directory Certificate Services
+const (
+	AttributeCertificateRevocationList = "certificateRevocationList"
+	AttributeDeltaRevocationList       = "deltaRevocationList"
+)
+
+// LDAPConn is the part of an LDAP connection used to retrieve CRLs, so that it can be replaced by a stub
+type LDAPConn interface {
+	StartTLS(config *tls.Config) error
+	Bind(username, password string) error
+	Search(searchRequest *ldap.SearchRequest) (*ldap.SearchResult, error)
+	Close() error
+}
+
+// LDAPDialer opens a connection to an LDAP server given as ldap://host:port or ldaps://host:port
+type LDAPDialer func(ctx context.Context, serverURL string) (LDAPConn, error)
+
+// LDAPURL is a parsed RFC 4516 LDAP URL: ldap://host:port/dn?attributes?scope?filter?extensions
+type LDAPURL struct {
+	Scheme     string   // ldap or ldaps
+	Host       string   // host:port, empty if the URL leaves the server to the client, e.g. ldap:///CN=...
+	DN         string   // Base DN of the search
+	Attributes []string // Attributes to retrieve, certificateRevocationList if the URL lists none
+	Scope      int      // ldap.ScopeBaseObject, ldap.ScopeSingleLevel or ldap.ScopeWholeSubtree
+	Filter     string
+	Extensions []string
+}
+
+// IsLDAPURL reports whether a URL has the ldap or ldaps scheme
+func IsLDAPURL(rawURL string) bool {
+	scheme, _, found := strings.Cut(rawURL, "://")
+	return found && (strings.EqualFold(scheme, "ldap") || strings.EqualFold(scheme, "ldaps"))
+}
+
+// ParseLDAPURL parses an LDAP URL as used in CRL distribution points, applying the defaults of RFC 4516
+// except for the attributes, which default to certificateRevocationList.
+// URLs with critical extensions are rejected, since no extensions are supported.
+func ParseLDAPURL(rawURL string) (*LDAPURL, error) {
+	scheme, rest, found := strings.Cut(rawURL, "://")
+	if !found || !IsLDAPURL(rawURL) {
+		return nil, fmt.Errorf("not an LDAP URL: %s", rawURL)
+	}
+	hostPart, rest, _ := strings.Cut(rest, "/")
+	host, err := url.PathUnescape(hostPart)
+	if err != nil {
+		return nil, fmt.Errorf("invalid host in LDAP URL %s: %w", rawURL, err)
+	}
+
+	parts := strings.SplitN(rest, "?", 5)
+	for len(parts) < 5 {
+		parts = append(parts, "")
+	}
+	for i, part := range parts {
+		if parts[i], err = url.PathUnescape(part); err != nil {
+			return nil, fmt.Errorf("invalid escape in LDAP URL %s: %w", rawURL, err)
+		}
+	}
+
+	ldapURL := &LDAPURL{
+		Scheme: strings.ToLower(scheme),
+		Host:   host,
+		DN:     parts[0],
+		Scope:  ldap.ScopeBaseObject,
+		Filter: "(objectClass=*)",
+	}
+	if ldapURL.DN == "" {
+		return nil, fmt.Errorf("no DN in LDAP URL %s", rawURL)
+	}
+	if _, err := ldap.ParseDN(ldapURL.DN); err != nil {
+		return nil, fmt.Errorf("invalid DN in LDAP URL %s: %w", rawURL, err)
+	}
+	for _, attribute := range strings.Split(parts[1], ",") {
+		if attribute = strings.TrimSpace(attribute); attribute != "" {
+			ldapURL.Attributes = append(ldapURL.Attributes, attribute)
+		}
+	}
+	if len(ldapURL.Attributes) == 0 {
+		ldapURL.Attributes = []string{AttributeCertificateRevocationList}
+	}
+	switch strings.ToLower(parts[2]) {
+	case "", "base":
+	case "one":
+		ldapURL.Scope = ldap.ScopeSingleLevel
+	case "sub":
+		ldapURL.Scope = ldap.ScopeWholeSubtree
+	default:
+		return nil, fmt.Errorf("invalid scope %q in LDAP URL %s", parts[2], rawURL)
+	}
+	if parts[3] != "" {
+		ldapURL.Filter = parts[3]
+		if !strings.HasPrefix(ldapURL.Filter, "(") {
+			ldapURL.Filter = "(" + ldapURL.Filter + ")"
+		}
+	}
+	for _, extension := range strings.Split(parts[4], ",") {
+		if extension == "" {
+			continue
+		}
+		if strings.HasPrefix(extension, "!") {
+			return nil, fmt.Errorf("unsupported critical extension %q in LDAP URL %s", extension, rawURL)
+		}
+		ldapURL.Extensions = append(ldapURL.Extensions, extension)
+	}
+	return ldapURL, nil
+}
+
+// Name returns the value of the first RDN of the DN, e.g. "NHN Internal CA" for "CN=NHN Internal CA,CN=CDP,..."
+func (u *LDAPURL) Name() string {
+	dn, err := ldap.ParseDN(u.DN)
+	if err != nil || len(dn.RDNs) == 0 || len(dn.RDNs[0].Attributes) == 0 {
+		return ""
+	}
+	return dn.RDNs[0].Attributes[0].Value
+}
+
+// searchAttributes returns the attributes to request, adding the ;binary option servers such as Active Directory expect
+func (u *LDAPURL) searchAttributes() []string {
+	var attributes []string
+	for _, attribute := range u.Attributes {
+		attributes = append(attributes, attribute)
+		if !strings.Contains(attribute, ";") {
+			attributes = append(attributes, attribute+";binary")
+		}
+	}
+	return attributes
+}
+
+// dialLDAP returns the LDAPDialer used when FetcherConfig has none, connecting with the TLS settings of the fetcher
+func dialLDAP(config FetcherConfig, tlsConfig *tls.Config) LDAPDialer {
+	return func(ctx context.Context, serverURL string) (LDAPConn, error) {
+		dialer := &net.Dialer{Timeout: config.ConnectTimeout}
+		if deadline, ok := ctx.Deadline(); ok {
+			dialer.Deadline = deadline
+		}
+		conn, err := ldap.DialURL(serverURL, ldap.DialWithDialer(dialer), ldap.DialWithTLSConfig(tlsConfig))
+		if err != nil {
+			return nil, err
+		}
+		conn.SetTimeout(config.ReadTimeout)
+		return conn, nil
+	}
+}
+
+// fetchLDAPOnce performs a single LDAP search for the CRL and reports whether a failure is worth retrying
+func (f *Fetcher) fetchLDAPOnce(ctx context.Context, crlURL string) (*FetchResult, bool, error) {
+	ldapURL, err := ParseLDAPURL(crlURL)
+	if err != nil {
+		return nil, false, err
+	}
+	host := ldapURL.Host
+	if host == "" {
+		host = f.config.LDAPDefaultHost
+	}
+	if host == "" {
+		return nil, false, fmt.Errorf("LDAP URL %s names no server, and no default LDAP host is configured", crlURL)
+	}
+
+	attemptCtx, cancel := context.WithTimeout(ctx, f.config.ConnectTimeout+f.config.ReadTimeout)
+	defer cancel()
+
+	conn, err := f.ldapDial(attemptCtx, ldapURL.Scheme+"://"+host)
+	if err != nil {
+		return nil, ctx.Err() == nil, err
+	}
+	// Ensure the connection is closed when done, and interrupt the search if the context ends first
+	defer conn.Close()
+	stop := context.AfterFunc(attemptCtx, func() { conn.Close() })
+	defer stop()
+
+	if f.config.LDAPBindDN != "" {
+		// A simple bind sends the password as is, so on ldap:// the connection is upgraded with StartTLS first
+		if ldapURL.Scheme == "ldap" {
+			tlsConfig := f.tlsConfig.Clone()
+			if tlsConfig.ServerName == "" {
+				tlsConfig.ServerName = hostname(host)
+			}
+			if err := conn.StartTLS(tlsConfig); err != nil {
+				return nil, ctx.Err() == nil && retryableLDAPError(err), fmt.Errorf("StartTLS with %s failed, not sending the bind password in cleartext: %w", host, err)
+			}
+		}
+		if err := conn.Bind(f.config.LDAPBindDN, f.config.LDAPBindPassword); err != nil {
+			return nil, ctx.Err() == nil && retryableLDAPError(err), fmt.Errorf("LDAP bind as %s failed: %w", f.config.LDAPBindDN, err)
+		}
+	}
+
+	searchRequest := ldap.NewSearchRequest(ldapURL.DN, ldapURL.Scope, ldap.NeverDerefAliases, 0, int(f.config.ReadTimeout.Seconds()), false,
+		ldapURL.Filter, ldapURL.searchAttributes(), nil)
+	searchResult, err := conn.Search(searchRequest)
+	if err != nil {
+		return nil, ctx.Err() == nil && retryableLDAPError(err), fmt.Errorf("LDAP search for %s failed: %w", ldapURL.DN, err)
+	}
+
+	data := revocationListFromEntries(searchResult.Entries, ldapURL.Attributes)
+	if data == nil {
+		return nil, false, fmt.Errorf("no %s found at %s", strings.Join(ldapURL.Attributes, " or "), ldapURL.DN)
+	}
+	if int64(len(data)) > f.config.MaxBodyBytes {
+		return nil, false, ErrBodyTooLarge
+	}
+
+	// LDAP has no conditional search, so an unchanged CRL is recognized by comparing it with the previous one
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if cached := f.cache[crlURL]; cached != nil && bytes.Equal(cached.Data, data) {
+		return &FetchResult{Data: cached.Data, NotModified: true}, false, nil
+	}
+	result := &FetchResult{Data: data}
+	f.cache[crlURL] = result
+	return result, false, nil
+}
+
+// revocationListFromEntries returns the first value of the first of the attributes found in the entries,
+// matching attribute names regardless of case and options such as ;binary
+func revocationListFromEntries(entries []*ldap.Entry, attributes []string) []byte {
+	for _, attribute := range attributes {
+		wanted, _, _ := strings.Cut(attribute, ";")
+		for _, entry := range entries {
+			for _, entryAttribute := range entry.Attributes {
+				name, _, _ := strings.Cut(entryAttribute.Name, ";")
+				if strings.EqualFold(name, wanted) && len(entryAttribute.ByteValues) > 0 && len(entryAttribute.ByteValues[0]) > 0 {
+					return entryAttribute.ByteValues[0]
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// retryableLDAPError reports whether an LDAP error is transient
+func retryableLDAPError(err error) bool {
+	var ldapErr *ldap.Error
+	if !errors.As(err, &ldapErr) {
+		return true
+	}
+	return ldap.IsErrorAnyOf(err, ldap.ErrorNetwork, ldap.LDAPResultBusy, ldap.LDAPResultUnavailable, ldap.LDAPResultTimeLimitExceeded)
+}
+
+// hostname returns the host of a host:port address, or the address itself if it has no port
+func hostname(address string) string {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return address
+	}
+	return host
+}
@@ -0,0 +1,220 @@
+package crl
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// stubLDAPConn answers searches from a fixed set of entries and records what it was asked
+type stubLDAPConn struct {
+	entries   []*ldap.Entry
+	searchErr error
+	startTLS  []string // ServerName of each StartTLS
+	binds     []string
+	requests  []*ldap.SearchRequest
+}
+
+func (c *stubLDAPConn) StartTLS(config *tls.Config) error {
+	c.startTLS = append(c.startTLS, config.ServerName)
+	return nil
+}
+
+func (c *stubLDAPConn) Bind(username, password string) error {
+	c.binds = append(c.binds, username+":"+password)
+	return nil
+}
+
+func (c *stubLDAPConn) Search(searchRequest *ldap.SearchRequest) (*ldap.SearchResult, error) {
+	c.requests = append(c.requests, searchRequest)
+	if c.searchErr != nil {
+		return nil, c.searchErr
+	}
+	return &ldap.SearchResult{Entries: c.entries}, nil
+}
+
+func (c *stubLDAPConn) Close() error {
+	return nil
+}
+
+// newStubFetcher returns a Fetcher that connects to the stub, and the server URLs it dialed
+func newStubFetcher(t *testing.T, conn *stubLDAPConn, config FetcherConfig) (*Fetcher, *[]string) {
+	t.Helper()
+	var dialed []string
+	config.MaxRetries = -1
+	config.LDAPDialer = func(ctx context.Context, serverURL string) (LDAPConn, error) {
+		dialed = append(dialed, serverURL)
+		return conn, nil
+	}
+	fetcher, err := NewFetcher(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fetcher, &dialed
+}
+
+func TestParseLDAPURL(t *testing.T) {
+	tests := []struct {
+		name       string
+		url        string
+		host       string
+		dn         string
+		attributes []string
+		scope      int
+		filter     string
+		wantErr    bool
+	}{
+		{
+			name:       "ADCS distribution point",
+			url:        "ldap:///CN=NHN%20Internal%20CA,CN=srv01,CN=CDP,CN=Public%20Key%20Services,DC=nhn,DC=no?certificateRevocationList?base?objectClass=cRLDistributionPoint",
+			dn:         "CN=NHN Internal CA,CN=srv01,CN=CDP,CN=Public Key Services,DC=nhn,DC=no",
+			attributes: []string{"certificateRevocationList"},
+			scope:      ldap.ScopeBaseObject,
+			filter:     "(objectClass=cRLDistributionPoint)",
+		},
+		{
+			name:       "defaults",
+			url:        "ldaps://dc01.nhn.no:636/CN=CA,DC=nhn,DC=no",
+			host:       "dc01.nhn.no:636",
+			dn:         "CN=CA,DC=nhn,DC=no",
+			attributes: []string{"certificateRevocationList"},
+			scope:      ldap.ScopeBaseObject,
+			filter:     "(objectClass=*)",
+		},
+		{
+			name:       "delta, several attributes and subtree scope",
+			url:        "ldap://dc01/DC=nhn,DC=no?deltaRevocationList;binary,certificateRevocationList?sub?(cn=CA)",
+			host:       "dc01",
+			dn:         "DC=nhn,DC=no",
+			attributes: []string{"deltaRevocationList;binary", "certificateRevocationList"},
+			scope:      ldap.ScopeWholeSubtree,
+			filter:     "(cn=CA)",
+		},
+		{
+			name:       "single level scope with non-critical extension",
+			url:        "ldap://dc01/DC=nhn,DC=no??one??e-bindname=cn=x",
+			host:       "dc01",
+			dn:         "DC=nhn,DC=no",
+			attributes: []string{"certificateRevocationList"},
+			scope:      ldap.ScopeSingleLevel,
+			filter:     "(objectClass=*)",
+		},
+		{name: "critical extension", url: "ldap://dc01/DC=nhn,DC=no????!e-bindname=cn=x", wantErr: true},
+		{name: "invalid scope", url: "ldap://dc01/DC=nhn,DC=no??tree", wantErr: true},
+		{name: "no DN", url: "ldap://dc01/", wantErr: true},
+		{name: "not LDAP", url: "http://crl.nhn.no/ca.crl", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ldapURL, err := ParseLDAPURL(tt.url)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseLDAPURL(%q) succeeded, want error", tt.url)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseLDAPURL(%q): %v", tt.url, err)
+			}
+			if ldapURL.Host != tt.host || ldapURL.DN != tt.dn || ldapURL.Scope != tt.scope || ldapURL.Filter != tt.filter ||
+				!slices.Equal(ldapURL.Attributes, tt.attributes) {
+				t.Errorf("ParseLDAPURL(%q) = %+v", tt.url, ldapURL)
+			}
+		})
+	}
+}
+
+func TestFetchLDAPDefaultHostAndBinaryAttribute(t *testing.T) {
+	conn := &stubLDAPConn{entries: []*ldap.Entry{{
+		DN: "CN=CA,CN=CDP,DC=nhn,DC=no",
+		Attributes: []*ldap.EntryAttribute{
+			{Name: "deltaRevocationList;binary", ByteValues: [][]byte{[]byte("delta")}},
+			{Name: "certificateRevocationList;binary", ByteValues: [][]byte{[]byte("base")}},
+		},
+	}}}
+	fetcher, dialed := newStubFetcher(t, conn, FetcherConfig{LDAPDefaultHost: "dc01.nhn.no:389"})
+
+	result, err := fetcher.Fetch(context.Background(), "ldap:///CN=CA,CN=CDP,DC=nhn,DC=no?certificateRevocationList?base?objectClass=cRLDistributionPoint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(result.Data) != "base" {
+		t.Errorf("got %q, want the certificateRevocationList;binary value", result.Data)
+	}
+	if !slices.Equal(*dialed, []string{"ldap://dc01.nhn.no:389"}) {
+		t.Errorf("dialed %v, want the default host", *dialed)
+	}
+	if want := []string{"certificateRevocationList", "certificateRevocationList;binary"}; !slices.Equal(conn.requests[0].Attributes, want) {
+		t.Errorf("requested attributes %v, want %v", conn.requests[0].Attributes, want)
+	}
+	if len(conn.binds) != 0 || len(conn.startTLS) != 0 {
+		t.Errorf("anonymous fetch bound %v with StartTLS %v", conn.binds, conn.startTLS)
+	}
+
+	result, err = fetcher.Fetch(context.Background(), "ldap:///CN=CA,CN=CDP,DC=nhn,DC=no?deltaRevocationList?base?objectClass=cRLDistributionPoint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(result.Data) != "delta" {
+		t.Errorf("got %q, want the deltaRevocationList;binary value", result.Data)
+	}
+
+	// The same CRL again is reported as not modified
+	result, err = fetcher.Fetch(context.Background(), "ldap:///CN=CA,CN=CDP,DC=nhn,DC=no?deltaRevocationList?base?objectClass=cRLDistributionPoint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.NotModified {
+		t.Error("unchanged CRL not reported as not modified")
+	}
+}
+
+func TestFetchLDAPErrors(t *testing.T) {
+	conn := &stubLDAPConn{entries: []*ldap.Entry{{DN: "CN=CA,DC=nhn,DC=no"}}}
+	fetcher, dialed := newStubFetcher(t, conn, FetcherConfig{})
+
+	if _, err := fetcher.Fetch(context.Background(), "ldap:///CN=CA,DC=nhn,DC=no"); err == nil {
+		t.Error("fetch without server and default host succeeded")
+	}
+	if len(*dialed) != 0 {
+		t.Errorf("dialed %v without a server", *dialed)
+	}
+	if _, err := fetcher.Fetch(context.Background(), "ldap://dc01/CN=CA,DC=nhn,DC=no"); err == nil {
+		t.Error("fetch of an entry without CRL attribute succeeded")
+	}
+
+	conn.searchErr = ldap.NewError(ldap.LDAPResultNoSuchObject, errors.New("no such object"))
+	if _, err := fetcher.Fetch(context.Background(), "ldap://dc01/CN=CA,DC=nhn,DC=no"); !ldap.IsErrorWithCode(err, ldap.LDAPResultNoSuchObject) {
+		t.Errorf("got %v, want the LDAP result code", err)
+	}
+}
+
+func TestFetchLDAPBindUsesStartTLS(t *testing.T) {
+	conn := &stubLDAPConn{entries: []*ldap.Entry{{
+		DN:         "CN=CA,DC=nhn,DC=no",
+		Attributes: []*ldap.EntryAttribute{{Name: "certificateRevocationList", ByteValues: [][]byte{[]byte("base")}}},
+	}}}
+	fetcher, _ := newStubFetcher(t, conn, FetcherConfig{LDAPBindDN: "CN=trawler,DC=nhn,DC=no", LDAPBindPassword: "secret"})
+
+	if _, err := fetcher.Fetch(context.Background(), "ldap://dc01.nhn.no:389/CN=CA,DC=nhn,DC=no"); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(conn.startTLS, []string{"dc01.nhn.no"}) {
+		t.Errorf("StartTLS %v, want once for dc01.nhn.no", conn.startTLS)
+	}
+	if !slices.Equal(conn.binds, []string{"CN=trawler,DC=nhn,DC=no:secret"}) {
+		t.Errorf("binds %v", conn.binds)
+	}
+
+	// ldaps:// is encrypted from the start
+	if _, err := fetcher.Fetch(context.Background(), "ldaps://dc01.nhn.no/CN=CA,DC=nhn,DC=no"); err != nil {
+		t.Fatal(err)
+	}
+	if len(conn.startTLS) != 1 {
+		t.Errorf("StartTLS %v, want none for ldaps://", conn.startTLS)
+	}
+}
@@ -1,34 +1,32 @@
 package crl
 
 import (
+	"context"
 	"crypto/x509"
 	"crypto/x509/pkix"
 	"encoding/asn1"
-	"io"
-	"net/http"
 	"time"
 )
 
-// retrieveCertificateRevocationList fetches the CRL from the specified URL
-func RetrieveCertificateRevocationList(url string) ([]byte, error) {
-	resp, error := http.Get(url)
-	if error != nil {
-		return nil, error
-	}
-	// Ensure the response body is closed after reading
-	defer resp.Body.Close()
+// defaultFetcher is used by RetrieveCertificateRevocationList
+var defaultFetcher, _ = NewFetcher(FetcherConfig{})
 
-	// Read the raw data to make it usable
-	data, err := io.ReadAll(resp.Body)
+// retrieveCertificateRevocationList fetches the CRL from the specified URL with the default fetcher settings
+func RetrieveCertificateRevocationList(ctx context.Context, url string) ([]byte, error) {
+	result, err := defaultFetcher.Fetch(ctx, url)
 	if err != nil {
 		return nil, err
 	}
-
-	return data, nil
+	return result.Data, nil
 } // func retrieveCertificateRevocationList
 
-// parseCertificateRevocationList parses the raw CRL data into a structured x509.RevocationList
+// parseCertificateRevocationList parses the raw CRL data, DER or PEM encoded, into a structured x509.RevocationList.
+// The Raw field of the result always holds the DER encoding.
 func ParseCertificateRevocationList(data []byte) (*x509.RevocationList, error) {
+	data, err := DecodePEM(data)
+	if err != nil {
+		return nil, err
+	}
 	// Parse and output the data
 	crl, err := x509.ParseRevocationList(data)
 	if err != nil {
@@ -37,12 +35,13 @@ func ParseCertificateRevocationList(data []byte) (*x509.RevocationList, error) {
 	return crl, nil
 } // func parseCertificateRevocationList
 
+// ParseCertificate returns the first certificate in data, in any of the forms accepted by ParseCertificates
 func ParseCertificate(data []byte) (*x509.Certificate, error) {
-	cert, err := x509.ParseCertificate(data)
+	certs, err := ParseCertificates(data)
 	if err != nil {
 		return nil, err
 	}
-	return cert, nil
+	return certs[0], nil
 }
 
 func TimeToUpdateCRL(nextUpdate time.Time, nextCRLPublish time.Time, updateThreshold time.Duration) bool {
@@ -68,11 +67,13 @@ func FindExtension(extensions []pkix.Extension, oid asn1.ObjectIdentifier) *pkix
 	return nil
 } // func findExtension
 
+// OIDNextCRLPublish is the Microsoft extension in which ADCS announces when it publishes the next CRL
+var OIDNextCRLPublish = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 311, 21, 4}
+
 func findNextPublishExtensionValue(extensions []pkix.Extension, nextPublishTime *time.Time) error {
-	NEXT_PUBLISH_OID := asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 311, 21, 7}
-	nextCRLPublish := FindExtension(extensions, NEXT_PUBLISH_OID)
+	nextCRLPublish := FindExtension(extensions, OIDNextCRLPublish)
 	if nextCRLPublish != nil {
-		_, err := asn1.Unmarshal(nextCRLPublish.Value, &nextPublishTime)
+		_, err := asn1.Unmarshal(nextCRLPublish.Value, nextPublishTime)
 		if err != nil {
 			return err
 		}
@@ -0,0 +1,34 @@
+package crl
+
+import (
+	"bytes"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrCRLRollback is returned when a CRL is older than the version that is already published
+var ErrCRLRollback = errors.New("CRL rollback detected")
+
+// CheckRollback verifies that the candidate CRL is not older than the currently published CRL.
+// Replacing a CRL with an older one could un-revoke certificates, so the CRL Number and ThisUpdate must never decrease.
+// The CRL Numbers of CRLs signed by different keys are not compared, since they are not related,
+// but ThisUpdate is, so that a CRL signed by the previous key cannot replace one signed after a key rollover.
+func CheckRollback(published *x509.RevocationList, candidate *x509.RevocationList) error {
+	if published == nil || candidate == nil {
+		return nil
+	}
+	sameKey := len(published.AuthorityKeyId) == 0 || len(candidate.AuthorityKeyId) == 0 || bytes.Equal(published.AuthorityKeyId, candidate.AuthorityKeyId)
+
+	if sameKey && published.Number != nil && candidate.Number != nil {
+		if candidate.Number.Cmp(published.Number) < 0 {
+			return fmt.Errorf("%w: CRL Number %s is lower than published CRL Number %s", ErrCRLRollback, candidate.Number, published.Number)
+		}
+	}
+	if candidate.ThisUpdate.Before(published.ThisUpdate) {
+		return fmt.Errorf("%w: ThisUpdate %s is before published ThisUpdate %s", ErrCRLRollback,
+			candidate.ThisUpdate.Format(time.RFC3339), published.ThisUpdate.Format(time.RFC3339))
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package crl
+
+import (
+	"math/rand"
+	"time"
+)
+
+// ScheduleConfig controls how often a CRL is fetched based on its validity
+type ScheduleConfig struct {
+	LeadThreshold time.Duration // How long before NextUpdate/NextCRLPublish to start fetching aggressively
+	MinInterval   time.Duration // Shortest time between two fetches of the same CRL
+	MaxInterval   time.Duration // Longest time between two fetches of the same CRL
+	JitterPercent int           // Random variation applied to the interval, in percent
+}
+
+// NextFetchTime calculates when a CRL should be fetched again.
+// CRLs close to (or past) their NextUpdate or NextCRLPublish are fetched every MinInterval,
+// others are fetched shortly before the lead threshold is reached, bounded by MaxInterval.
+func NextFetchTime(now time.Time, nextUpdate time.Time, nextCRLPublish time.Time, config ScheduleConfig) time.Time {
+	var interval time.Duration
+
+	if nextUpdate.IsZero() || TimeToUpdateCRL(nextUpdate, nextCRLPublish, config.LeadThreshold) {
+		interval = config.MinInterval
+	} else {
+		// Use the earlier of NextUpdate and NextCRLPublish
+		earliest := nextUpdate
+		if !nextCRLPublish.IsZero() && nextCRLPublish.Before(nextUpdate) {
+			earliest = nextCRLPublish
+		}
+		interval = earliest.Add(-config.LeadThreshold).Sub(now)
+	}
+
+	if interval < config.MinInterval {
+		interval = config.MinInterval
+	}
+	if config.MaxInterval > 0 && interval > config.MaxInterval {
+		interval = config.MaxInterval
+	}
+
+	// Spread fetches so that CRLs from the same CA are not all fetched at the same moment
+	if config.JitterPercent > 0 {
+		jitter := int64(interval) * int64(config.JitterPercent) / 100
+		if jitter > 0 {
+			interval += time.Duration(rand.Int63n(2*jitter+1) - jitter)
+		}
+		if interval < config.MinInterval {
+			interval = config.MinInterval
+		}
+	}
+
+	return now.Add(interval)
+}
@@ -0,0 +1,82 @@
+package crl
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"math/big"
+	"testing"
+	"time"
+)
+
+// TestNextFetchTimeUsesNextCRLPublish checks that the ADCS Next CRL Publish extension of a CRL drives the next fetch
+func TestNextFetchTimeUsesNextCRLPublish(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now().UTC().Truncate(time.Second)
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test CA"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(24 * time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caCert, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// ADCS encodes Next CRL Publish as UTCTime, here well before NextUpdate
+	nextPublish := now.Add(6 * time.Hour)
+	nextPublishValue, err := asn1.Marshal(nextPublish)
+	if err != nil {
+		t.Fatal(err)
+	}
+	crlDER, err := x509.CreateRevocationList(rand.Reader, &x509.RevocationList{
+		Number:          big.NewInt(1),
+		ThisUpdate:      now,
+		NextUpdate:      now.Add(7 * 24 * time.Hour),
+		ExtraExtensions: []pkix.Extension{{Id: OIDNextCRLPublish, Value: nextPublishValue}},
+	}, caCert, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decodedCRL, err := ParseCertificateRevocationList(crlDER)
+	if err != nil {
+		t.Fatal(err)
+	}
+	valid, hasNextPublish, nextPublishTime, err := IsCRLValid(decodedCRL, caCert)
+	if err != nil {
+		t.Fatalf("IsCRLValid: %v", err)
+	}
+	if !valid || !hasNextPublish {
+		t.Fatalf("IsCRLValid = valid %v, nextPublish %v, want both true", valid, hasNextPublish)
+	}
+	if !nextPublishTime.Equal(nextPublish) {
+		t.Fatalf("NextCRLPublish = %s, want %s", nextPublishTime, nextPublish)
+	}
+
+	config := ScheduleConfig{
+		LeadThreshold: time.Hour,
+		MinInterval:   2 * time.Minute,
+		MaxInterval:   24 * time.Hour,
+	}
+
+	// Without jitter the CRL is due one lead threshold before NextCRLPublish, not before NextUpdate
+	nextFetch := NextFetchTime(now, decodedCRL.NextUpdate, nextPublishTime, config)
+	if want := nextPublish.Add(-time.Hour); !nextFetch.Equal(want) {
+		t.Fatalf("next fetch at %s, want %s", nextFetch, want)
+	}
+}
@@ -1,6 +1,7 @@
 package crl
 
 import (
+	"bytes"
 	"crypto/x509"
 	"crypto/x509/pkix"
 	"fmt"
@@ -37,6 +38,54 @@ func IsCRLValid(crlData *x509.RevocationList, certData *x509.Certificate) (valid
 	}
 }
 
+// CACertificate is a CA certificate and the file it was read from, a candidate signer of CRLs
+type CACertificate struct {
+	FileName    string
+	Certificate *x509.Certificate
+}
+
+// Relations between the signer of a CRL and the certificate file configured for it
+const (
+	SignerExpected   = "expected"   // A certificate in the configured file, or any known certificate if none is configured
+	SignerRollover   = "rollover"   // Another certificate with the subject of the configured one, e.g. after the CA renewed its key
+	SignerUnexpected = "unexpected" // A known certificate of another CA
+	SignerUnknown    = "unknown"    // None of the known certificates
+)
+
+// FindCRLSigner returns the CA certificate that signed the CRL and how it relates to the certificates in certFileName.
+// As with certificates, the issuer name and key identifier narrow down the candidates and the signature decides,
+// so any number of certificates of the same CA, such as the CA(1) and CA(2) renewals of ADCS, can be valid at once.
+func FindCRLSigner(caCertificates []CACertificate, certFileName string, revocationList *x509.RevocationList) (*CACertificate, string) {
+	var signer *CACertificate
+	for i := range caCertificates {
+		ca := caCertificates[i].Certificate
+		if !bytes.Equal(ca.RawSubject, revocationList.RawIssuer) {
+			continue
+		}
+		if len(revocationList.AuthorityKeyId) > 0 && len(ca.SubjectKeyId) > 0 && !bytes.Equal(revocationList.AuthorityKeyId, ca.SubjectKeyId) {
+			continue
+		}
+		if revocationList.CheckSignatureFrom(ca) != nil {
+			continue
+		}
+		if certFileName == "" || caCertificates[i].FileName == certFileName {
+			return &caCertificates[i], SignerExpected
+		}
+		if signer == nil {
+			signer = &caCertificates[i]
+		}
+	}
+	if signer == nil {
+		return nil, SignerUnknown
+	}
+	for _, configured := range caCertificates {
+		if configured.FileName == certFileName && bytes.Equal(configured.Certificate.RawSubject, signer.Certificate.RawSubject) {
+			return signer, SignerRollover
+		}
+	}
+	return signer, SignerUnexpected
+}
+
 func validateCRLToCertificate(crlData *x509.RevocationList, certData *x509.Certificate) (bool, error) {
 	// Verify the CRL signature using the issuer's public key
 	err := crlData.CheckSignatureFrom(certData)
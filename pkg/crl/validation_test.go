@@ -0,0 +1,95 @@
+package crl
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"testing"
+	"time"
+)
+
+// newTestCA returns a self-signed CA certificate with a new key, read from the given file
+func newTestCA(t *testing.T, fileName string, commonName string, keyID byte) (CACertificate, crypto.Signer) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(int64(keyID)),
+		Subject:               pkix.Name{CommonName: commonName},
+		SubjectKeyId:          []byte{keyID},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certificate, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return CACertificate{FileName: fileName, Certificate: certificate}, key
+}
+
+// newTestCRLFrom returns a CRL signed by the CA
+func newTestCRLFrom(t *testing.T, ca CACertificate, key crypto.Signer) *x509.RevocationList {
+	t.Helper()
+	now := time.Now()
+	der, err := x509.CreateRevocationList(rand.Reader, &x509.RevocationList{
+		Number:     big.NewInt(1),
+		ThisUpdate: now,
+		NextUpdate: now.Add(24 * time.Hour),
+	}, ca.Certificate, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	revocationList, err := x509.ParseRevocationList(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return revocationList
+}
+
+func TestFindCRLSigner(t *testing.T) {
+	internalCA, internalKey := newTestCA(t, "NHN Internal CA.crt", "NHN Internal CA", 1)
+	renewedCA, renewedKey := newTestCA(t, "NHN Internal CA(1).crt", "NHN Internal CA", 2)
+	otherCA, otherKey := newTestCA(t, "NHN Other CA.crt", "NHN Other CA", 3)
+	unknownCA, unknownKey := newTestCA(t, "NHN Unknown CA.crt", "NHN Unknown CA", 4)
+	caCertificates := []CACertificate{internalCA, renewedCA, otherCA}
+
+	tests := []struct {
+		name         string
+		crl          *x509.RevocationList
+		certFileName string
+		signer       string
+		relation     string
+	}{
+		{"configured CA", newTestCRLFrom(t, internalCA, internalKey), "NHN Internal CA.crt", "NHN Internal CA.crt", SignerExpected},
+		{"no configured file", newTestCRLFrom(t, otherCA, otherKey), "", "NHN Other CA.crt", SignerExpected},
+		{"renewed key of the configured CA", newTestCRLFrom(t, renewedCA, renewedKey), "NHN Internal CA.crt", "NHN Internal CA(1).crt", SignerRollover},
+		{"another CA", newTestCRLFrom(t, otherCA, otherKey), "NHN Internal CA.crt", "NHN Other CA.crt", SignerUnexpected},
+		{"unknown key", newTestCRLFrom(t, unknownCA, unknownKey), "NHN Internal CA.crt", "", SignerUnknown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signer, relation := FindCRLSigner(caCertificates, tt.certFileName, tt.crl)
+			signerFile := ""
+			if signer != nil {
+				signerFile = signer.FileName
+			}
+			if signerFile != tt.signer || relation != tt.relation {
+				t.Errorf("FindCRLSigner = %q, %s, want %q, %s", signerFile, relation, tt.signer, tt.relation)
+			}
+		})
+	}
+}
@@ -0,0 +1,228 @@
+package discovery
+
+import (
+	"bytes"
+	"crypto/x509"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"trawler/pkg/crl"
+)
+
+// supportedSchemes are the URL schemes of distribution points that CRLs can be retrieved from
+var supportedSchemes = map[string]bool{
+	"http":  true,
+	"https": true,
+	"ldap":  true,
+	"ldaps": true,
+}
+
+// Certificate is a certificate read from a file
+type Certificate = crl.CACertificate
+
+// CRL is a CRL found in the distribution points of a certificate
+type CRL struct {
+	Name         string
+	URL          string
+	Mirrors      []string // Further URLs the CRL was found at
+	CertFileName string   // File name of the CA certificate the CRL is signed with
+	BaseCrl      string   // Name of the base CRL, set for delta CRLs found in the Freshest CRL extension
+	FoundIn      string   // File name of the certificate the distribution point was found in
+}
+
+// IsDelta reports whether the CRL is a delta CRL
+func (c CRL) IsDelta() bool {
+	return c.BaseCrl != ""
+}
+
+// ReadCertificates reads the certificates in a directory, including all certificates of PEM and PKCS#7 bundles,
+// skipping files that are not certificates
+func ReadCertificates(dir string) ([]Certificate, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var certificates []Certificate
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		bundle, err := crl.ParseCertificates(data)
+		if err != nil {
+			continue
+		}
+		for _, certificate := range bundle {
+			certificates = append(certificates, Certificate{FileName: entry.Name(), Certificate: certificate})
+		}
+	}
+	return certificates, nil
+}
+
+// Discover returns the CRLs in the distribution points of the certificates, each with the CA certificate it is signed with.
+// A distribution point names the CRL of the issuer of the certificate, so the issuer must be among the CA certificates.
+// Certificates whose issuer is unknown are skipped and reported in the returned errors.
+// CRLs are identified by the file name in their URL, so a CRL found at several URLs is returned once,
+// with the first URL it was found at and the others as mirrors.
+func Discover(caCertificates []Certificate, certificates []Certificate) ([]CRL, []error) {
+	var discovered []CRL
+	var errs []error
+	byName := make(map[string]int) // Index in discovered by name
+	add := func(found CRL) {
+		i, exists := byName[found.Name]
+		if !exists {
+			byName[found.Name] = len(discovered)
+			discovered = append(discovered, found)
+			return
+		}
+		existing := &discovered[i]
+		if existing.URL == found.URL || slices.Contains(existing.Mirrors, found.URL) {
+			return
+		}
+		existing.Mirrors = append(existing.Mirrors, found.URL)
+	}
+
+	for _, certificate := range certificates {
+		baseURLs := supportedURLs(certificate.Certificate.CRLDistributionPoints)
+		deltaURLs, err := crl.FreshestCRLURLs(certificate.Certificate.Extensions)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", certificate.FileName, err))
+		}
+		deltaURLs = supportedURLs(deltaURLs)
+		if len(baseURLs) == 0 && len(deltaURLs) == 0 {
+			continue
+		}
+
+		issuer := FindIssuer(caCertificates, certificate.Certificate)
+		if issuer == nil {
+			errs = append(errs, fmt.Errorf("%s: issuer %q is not among the CA certificates", certificate.FileName, certificate.Certificate.Issuer))
+			continue
+		}
+
+		baseName := ""
+		for _, baseURL := range baseURLs {
+			name, err := NameFromURL(baseURL)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", certificate.FileName, err))
+				continue
+			}
+			if baseName == "" {
+				baseName = name
+			}
+			add(CRL{Name: name, URL: baseURL, CertFileName: issuer.FileName, FoundIn: certificate.FileName})
+		}
+		for _, deltaURL := range deltaURLs {
+			if baseName == "" {
+				errs = append(errs, fmt.Errorf("%s: delta CRL %s has no base CRL in the distribution points", certificate.FileName, deltaURL))
+				break
+			}
+			name, err := NameFromURL(deltaURL)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", certificate.FileName, err))
+				continue
+			}
+			if name == baseName {
+				name += "+"
+			}
+			add(CRL{Name: name, URL: deltaURL, CertFileName: issuer.FileName, BaseCrl: baseName, FoundIn: certificate.FileName})
+		}
+	}
+
+	// Base CRLs first, so that they are listed before their delta CRLs
+	sort.SliceStable(discovered, func(i, j int) bool {
+		return !discovered[i].IsDelta() && discovered[j].IsDelta()
+	})
+	return discovered, errs
+}
+
+// SelfCRLURLs returns the URLs a CA most likely publishes its own CRL at. A CA certificate only names the CRL of
+// its issuer, but the CAs of a PKI commonly publish their CRLs side by side under the name of the CA, e.g.
+// "http://crl.nhn.no/crl/NHN%20Internal%20CA.crl" next to "http://crl.nhn.no/crl/NHN%20Root%20CA.crl".
+// The URLs are formed by replacing the file name in the HTTP distribution points of the CA certificate with its
+// common name, so the CRL found there must still be checked to be signed by the CA.
+func SelfCRLURLs(caCertificate *x509.Certificate) []string {
+	commonName := caCertificate.Subject.CommonName
+	if !caCertificate.IsCA || commonName == "" {
+		return nil
+	}
+	var urls []string
+	for _, rawURL := range supportedURLs(caCertificate.CRLDistributionPoints) {
+		parsed, err := url.Parse(rawURL)
+		if err != nil || (!strings.EqualFold(parsed.Scheme, "http") && !strings.EqualFold(parsed.Scheme, "https")) {
+			continue
+		}
+		parsed.Path = path.Join(path.Dir(parsed.Path), commonName+".crl")
+		parsed.RawPath = ""
+		parsed.RawQuery, parsed.Fragment = "", ""
+		if selfURL := parsed.String(); selfURL != rawURL && !slices.Contains(urls, selfURL) {
+			urls = append(urls, selfURL)
+		}
+	}
+	return urls
+}
+
+// FindIssuer returns the CA certificate that signed the certificate, or nil if it is not among them.
+// The subject and key identifier narrow down the candidates, the signature decides.
+func FindIssuer(caCertificates []Certificate, certificate *x509.Certificate) *Certificate {
+	for i := range caCertificates {
+		ca := caCertificates[i].Certificate
+		if !bytes.Equal(ca.RawSubject, certificate.RawIssuer) {
+			continue
+		}
+		if len(certificate.AuthorityKeyId) > 0 && len(ca.SubjectKeyId) > 0 && !bytes.Equal(certificate.AuthorityKeyId, ca.SubjectKeyId) {
+			continue
+		}
+		if certificate.CheckSignatureFrom(ca) == nil {
+			return &caCertificates[i]
+		}
+	}
+	return nil
+}
+
+// supportedURLs returns the URLs with a scheme CRLs can be retrieved from
+func supportedURLs(urls []string) []string {
+	var supported []string
+	for _, rawURL := range urls {
+		parsed, err := url.Parse(rawURL)
+		if err == nil && supportedSchemes[strings.ToLower(parsed.Scheme)] {
+			supported = append(supported, rawURL)
+		}
+	}
+	return supported
+}
+
+// NameFromURL derives the name of a CRL from the file name in its URL,
+// e.g. "NHN Internal CA - PROD" from "http://crl.nhn.no/crl/NHN%20Internal%20CA%20-%20PROD.crl",
+// or from the first RDN of the DN in an LDAP URL, e.g. "NHN Internal CA - PROD" from "ldap:///CN=NHN%20Internal%20CA%20-%20PROD,CN=CDP,..."
+func NameFromURL(rawURL string) (string, error) {
+	if crl.IsLDAPURL(rawURL) {
+		ldapURL, err := crl.ParseLDAPURL(rawURL)
+		if err != nil {
+			return "", err
+		}
+		if name := ldapURL.Name(); name != "" {
+			return name, nil
+		}
+		return "", fmt.Errorf("no name in the DN of CRL URL %s", rawURL)
+	}
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	name := path.Base(parsed.Path)
+	if strings.EqualFold(path.Ext(name), ".crl") {
+		name = strings.TrimSuffix(name, path.Ext(name))
+	}
+	if name == "" || name == "." || name == "/" {
+		return "", fmt.Errorf("no file name in CRL URL %s", rawURL)
+	}
+	return name, nil
+}
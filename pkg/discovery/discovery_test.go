@@ -0,0 +1,60 @@
+package discovery
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"slices"
+	"testing"
+)
+
+func TestSelfCRLURLs(t *testing.T) {
+	tests := []struct {
+		name        string
+		certificate *x509.Certificate
+		want        []string
+	}{
+		{
+			name: "intermediate next to its root",
+			certificate: &x509.Certificate{
+				IsCA:    true,
+				Subject: pkix.Name{CommonName: "NHN Internal CA - PROD"},
+				CRLDistributionPoints: []string{
+					"http://crl.nhn.no/crl/NHN%20Root%20CA.crl",
+					"ldap:///CN=NHN%20Root%20CA,CN=CDP,DC=nhn,DC=no?certificateRevocationList",
+					"http://crl2.nhn.no/NHN%20Root%20CA.crl",
+				},
+			},
+			want: []string{
+				"http://crl.nhn.no/crl/NHN%20Internal%20CA%20-%20PROD.crl",
+				"http://crl2.nhn.no/NHN%20Internal%20CA%20-%20PROD.crl",
+			},
+		},
+		{
+			name: "leaf certificate",
+			certificate: &x509.Certificate{
+				Subject:               pkix.Name{CommonName: "www.nhn.no"},
+				CRLDistributionPoints: []string{"http://crl.nhn.no/crl/NHN%20Internal%20CA.crl"},
+			},
+		},
+		{
+			name: "no distribution points",
+			certificate: &x509.Certificate{
+				IsCA:    true,
+				Subject: pkix.Name{CommonName: "NHN Root CA"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SelfCRLURLs(tt.certificate)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("SelfCRLURLs = %v, want %v", got, tt.want)
+			}
+			for _, selfURL := range got {
+				if name, err := NameFromURL(selfURL); err != nil || name != tt.certificate.Subject.CommonName {
+					t.Errorf("NameFromURL(%s) = %q, %v, want the common name", selfURL, name, err)
+				}
+			}
+		})
+	}
+}
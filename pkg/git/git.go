@@ -1,6 +1,7 @@
 package gitops
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"reflect"
@@ -9,6 +10,7 @@ import (
 	"trawler/pkg/storage"
 
 	git "github.com/go-git/go-git/v5"
+	gitconfig "github.com/go-git/go-git/v5/config"
 	"github.com/go-git/go-git/v5/plumbing/transport/http"
 )
 
@@ -111,6 +113,19 @@ func PullRepository(path string) error {
 	return nil
 }
 
+// Verifies that the remote repository can be reached with the configured credentials
+func HealthCheck(ctx context.Context) error {
+	remote := git.NewRemote(nil, &gitconfig.RemoteConfig{
+		Name: "origin",
+		URLs: []string{gitConfig.RepositoryURL},
+	})
+	_, err := remote.ListContext(ctx, &git.ListOptions{Auth: &http.BasicAuth{
+		Username: gitConfig.Username,
+		Password: gitConfig.AccessToken,
+	}})
+	return err
+}
+
 // Opens an existing Git repository at the specified path
 func OpenRepository(path string) (*git.Repository, error) {
 	repo, err := git.PlainOpen(path)
@@ -0,0 +1,203 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+	"trawler/pkg/logging"
+)
+
+// Checker reports the status of a component, and the error that caused it if not OK
+type Checker func(ctx context.Context) (status string, err error)
+
+// ComponentStatus is the latest result of a component's health check
+type ComponentStatus struct {
+	Name      string    `json:"name"`
+	Status    string    `json:"status"`
+	Critical  bool      `json:"critical"`
+	LastCheck time.Time `json:"lastCheck"`
+	LastError string    `json:"lastError,omitempty"`
+}
+
+type component struct {
+	checker  Checker
+	interval time.Duration
+	status   ComponentStatus
+	nextRun  time.Time
+}
+
+// Registry runs the health checks registered by each subsystem and aggregates their results.
+// A critical component that is unhealthy makes the whole service unhealthy, any other problem only degrades it.
+type Registry struct {
+	mu         sync.Mutex
+	components map[string]*component
+}
+
+// NewRegistry creates an empty health registry
+func NewRegistry() *Registry {
+	return &Registry{components: make(map[string]*component)}
+}
+
+// Register adds a periodic health check for a component, replacing any check registered under the same name
+func (r *Registry) Register(name string, interval time.Duration, critical bool, checker Checker) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	status := ComponentStatus{Name: name, Status: HealthStatusUnknown, Critical: critical}
+	if existing, exists := r.components[name]; exists {
+		// Keep the last result, so a re-registration does not reset the status to unknown
+		status.Status = existing.status.Status
+		status.LastCheck = existing.status.LastCheck
+		status.LastError = existing.status.LastError
+	}
+	r.components[name] = &component{checker: checker, interval: interval, status: status}
+}
+
+// Unregister removes the health check of a component
+func (r *Registry) Unregister(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.components, name)
+}
+
+// Names returns the names of all registered components
+func (r *Registry) Names() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	names := make([]string, 0, len(r.components))
+	for name := range r.components {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Start runs the registered checks when they are due until stopChan is closed.
+// tick is how often the registry looks for due checks.
+func (r *Registry) Start(tick time.Duration, stopChan <-chan struct{}) {
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+
+	r.runDueChecks()
+	for {
+		select {
+		case <-stopChan:
+			return
+		case <-ticker.C:
+			r.runDueChecks()
+		}
+	}
+}
+
+// CheckNow runs all registered checks immediately
+func (r *Registry) CheckNow() {
+	r.mu.Lock()
+	for _, c := range r.components {
+		c.nextRun = time.Time{}
+	}
+	r.mu.Unlock()
+	r.runDueChecks()
+}
+
+func (r *Registry) runDueChecks() {
+	now := time.Now()
+
+	r.mu.Lock()
+	due := make(map[string]*component)
+	for name, c := range r.components {
+		if !now.Before(c.nextRun) {
+			c.nextRun = now.Add(c.interval)
+			due[name] = c
+		}
+	}
+	r.mu.Unlock()
+
+	var checkWg sync.WaitGroup
+	for name, c := range due {
+		checkWg.Add(1)
+		go func(name string, c *component) {
+			defer checkWg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), c.interval)
+			defer cancel()
+
+			status, err := c.checker(ctx)
+			r.record(name, c, status, err)
+		}(name, c)
+	}
+	checkWg.Wait()
+}
+
+func (r *Registry) record(name string, c *component, status string, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Ignore results for components that were unregistered or replaced while the check ran
+	if r.components[name] != c {
+		return
+	}
+	if status != c.status.Status {
+		logHealthChange(name, c.status.Status, status, err)
+	}
+	c.status.Status = status
+	c.status.LastCheck = time.Now()
+	c.status.LastError = ""
+	if err != nil {
+		c.status.LastError = err.Error()
+	}
+}
+
+// Components returns the latest status of all registered components, sorted by name
+func (r *Registry) Components() []ComponentStatus {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	statuses := make([]ComponentStatus, 0, len(r.components))
+	for _, c := range r.components {
+		statuses = append(statuses, c.status)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
+
+// ComponentStatus returns the latest status of a single component
+func (r *Registry) ComponentStatus(name string) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if c, exists := r.components[name]; exists {
+		return c.status.Status
+	}
+	return HealthStatusUnknown
+}
+
+// Status returns the overall status of the service
+func (r *Registry) Status() string {
+	overall := HealthStatusOK
+	for _, status := range r.Components() {
+		switch status.Status {
+		case HealthStatusUnhealthy:
+			if status.Critical {
+				return HealthStatusUnhealthy
+			}
+			overall = HealthStatusDegraded
+		case HealthStatusDegraded:
+			overall = HealthStatusDegraded
+		}
+	}
+	return overall
+}
+
+func logHealthChange(name string, previous string, current string, err error) {
+	msg := fmt.Sprintf("Health of %s changed from %s to %s", name, previous, current)
+	if err != nil {
+		msg = fmt.Sprintf("%s: %v", msg, err)
+	}
+	switch current {
+	case HealthStatusOK:
+		logging.LogToConsole(logging.InfoLevel, logging.InfoEvent, msg)
+	case HealthStatusUnhealthy:
+		logging.LogToConsole(logging.ErrorLevel, logging.ErrorEvent, msg)
+	default:
+		logging.LogToConsole(logging.WarningLevel, logging.WarningEvent, msg)
+	}
+}
@@ -11,8 +11,14 @@ const (
 	HealthStatusUnknown = "unknown"
 )
 
+// CheckHealthStatus reports whether a service in the given status is able to serve traffic.
+// A degraded service is still operational, while an unhealthy one should not receive traffic.
+// An unknown status, e.g. before the first check has run, does not block traffic.
 func CheckHealthStatus(status string) bool {
-	//TODO: Add more advanced health checks if needed
-
-	return false
+	switch status {
+	case HealthStatusOK, HealthStatusDegraded, HealthStatusUnknown:
+		return true
+	default:
+		return false
+	}
 }
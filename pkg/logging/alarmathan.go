@@ -2,11 +2,13 @@ package logging
 
 import (
 	"bytes"
+	"context"
 	"crypto/sha256"
 	"encoding/json"
 	"fmt"
-	"log"
 	"net/http"
+	"strings"
+	"time"
 	config "trawler/pkg/config"
 
 	"github.com/k0kubun/pp"
@@ -77,8 +79,15 @@ const (
 	CriticalityCritical CriticalityLevel = "Kritisk"
 )
 
+// Client used to post alerts, with a timeout so an unresponsive webhook does not block alerting
+var webhookClient = &http.Client{Timeout: 10 * time.Second}
+
 // Send JSON to webhook
 func SendToWebhook(webhookURL string, data interface{}) error {
+	return sendJSONToWebhook(context.Background(), webhookURL, data)
+}
+
+func sendJSONToWebhook(ctx context.Context, webhookURL string, data interface{}) error {
 	// Marshal the data to JSON
 	jsonData, err := json.Marshal(data)
 	if err != nil {
@@ -87,33 +96,88 @@ func SendToWebhook(webhookURL string, data interface{}) error {
 
 	// pp.Printf("JSON Payload: %s\n", string(jsonData)) // Pretty print the JSON payload
 
+	return postToWebhook(ctx, webhookURL, "application/json", nil, jsonData)
+}
+
+// postToWebhook posts a body to a webhook and fails on any non-2xx response
+func postToWebhook(ctx context.Context, webhookURL string, contentType string, headers map[string]string, body []byte) error {
 	// Create the HTTP POST request
-	resp, err := http.Post(webhookURL, "application/json", bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := webhookClient.Do(req)
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
 
 	// Check response status
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
-		log.Printf("Webhook returned status: %d", resp.StatusCode)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook returned status: %d", resp.StatusCode)
 	}
 
 	return nil
 }
 
+// AlarmathanNotifier sends notifications to the Alarmathan webhook configured in the alarmathan section.
+// The section is read from the configuration in effect when sending, so a config refresh applies to it.
+type AlarmathanNotifier struct{}
+
+func (n *AlarmathanNotifier) Name() string {
+	return "Alarmathan"
+}
+
+// Notify sends the notification as an Alertmanager-shaped alarm, unless Alarmathan is not activated
+func (n *AlarmathanNotifier) Notify(ctx context.Context, notification Notification) error {
+	currentConfig := config.Current()
+	alarmathan := currentConfig.Configurations.Alarmathan
+	if !alarmathan.Activate || alarmathan.WebhookURL == "" {
+		LogToConsole(DebugLevel, DebugEvent, fmt.Sprintf("Alarmathan not activated, not sending %s alert: %s", notification.Status, notification.Summary))
+		return nil
+	}
+
+	description := notification.Description
+	if notification.Details != "" {
+		description = strings.TrimSpace(description + "\n\n" + notification.Details)
+	}
+	alarm := GenerateAlarm(*currentConfig,
+		notification.Summary,
+		notification.Criticality,
+		notification.Severity,
+		notification.Instance,
+		description)
+	alarm.Status = notification.Status
+	alarm.Alerts[0].Status = notification.Status
+	alarm.Alerts[0].Fingerprint = notification.Fingerprint
+	alarm.Alerts[0].StartsAt = notification.StartsAt.UTC().Format(time.RFC3339)
+	alarm.Alerts[0].EndsAt = notification.EndsAt.UTC().Format(time.RFC3339) // The zero time means the alert has no end yet
+
+	return sendJSONToWebhook(ctx, alarmathan.WebhookURL, *alarm)
+}
+
 // PrintAlarm pretty prints the Alarmathan struct
 func PrintAlarm(alarm *Alarmathan) {
 	pp.Printf("Alarm Details: %+v\n", alarm)
 }
 
-// Formats the alarm and returns a pointer to the filled alarm-object
+// Fingerprint identifies an alert by its name, instance and severity
+func Fingerprint(alertName string, instance string, severity SeverityLevel) string {
+	identity := fmt.Sprintf("%s:%s:%s", alertName, instance, severity)
+	hash := sha256.Sum256([]byte(identity))
+	return fmt.Sprintf("%x", hash)
+}
+
+// Formats a firing alarm starting now and returns a pointer to the filled alarm-object
 func GenerateAlarm(config config.Config, alertName string, criticality CriticalityLevel, severity SeverityLevel, instance string, description string) *Alarmathan {
 
 	// Generate fingerprint for the alert
-	identity := fmt.Sprintf("%s:%s:%s", alertName, instance, severity)
-	hash := sha256.Sum256([]byte(identity))
-	fingerprint := fmt.Sprintf("%x", hash)
+	fingerprint := Fingerprint(alertName, instance, severity)
 
 	// Create a alarmathan alarm
 	alarm := Alarmathan{
@@ -138,8 +202,8 @@ func GenerateAlarm(config config.Config, alertName string, criticality Criticali
 					Description: description,
 					Summary:     "",
 				},
-				StartsAt: "2025-12-15T12:00:00Z",
-				EndsAt:   "2025-12-15T16:00:00Z",
+				StartsAt: time.Now().UTC().Format(time.RFC3339),
+				EndsAt:   time.Time{}.Format(time.RFC3339), // Firing alerts have no end yet
 			},
 		},
 		GroupLabels: GroupLabels{
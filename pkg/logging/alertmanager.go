@@ -0,0 +1,205 @@
+package logging
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"sync"
+	"time"
+	cfg "trawler/pkg/config"
+)
+
+// Defaults for the alert lifecycle, used when not set in config
+const (
+	defaultRepeatIntervalMinutes = 240
+	defaultResolveTimeoutMinutes = 240
+	defaultAlertMaxRetries       = 5
+	alertInitialBackoff          = 1 * time.Second
+	alertMaxBackoff              = 30 * time.Second
+	alertQueueSize               = 100
+)
+
+// Alert statuses used by Alarmathan
+const (
+	AlertStatusFiring   = "firing"
+	AlertStatusResolved = "resolved"
+)
+
+// activeAlert is an alert that has been sent as firing and not resolved yet
+type activeAlert struct {
+	fingerprint string
+	report      ErrorReport
+	startsAt    time.Time
+	lastSeen    time.Time
+	lastSent    time.Time
+}
+
+// AlertManager keeps track of active alerts, so that each condition is sent once as firing,
+// repeated on an interval while it persists, and sent as resolved when it clears.
+// State changes are delivered to Alarmathan and to the notifiers defined in config.
+type AlertManager struct {
+	mu        sync.Mutex
+	instance  string
+	active    map[string]*activeAlert // Active alerts by key
+	workers   []*notifierWorker       // Alarmathan first, followed by the notifiers defined in config
+	notifiers []cfg.Notifier          // Config the notifiers were created from
+	workerWg  sync.WaitGroup
+}
+
+// NewAlertManager creates an alert manager and starts delivering notifications in the background.
+// The notifiers are created from config and recreated by Reconfigure, while the alarmathan section is read from the configuration in effect.
+func NewAlertManager(config *cfg.Config) *AlertManager {
+	m := &AlertManager{
+		instance:  alertInstance(),
+		active:    make(map[string]*activeAlert),
+		workers:   append([]*notifierWorker{newAlarmathanWorker()}, newNotifierWorkers(config.Configurations.Notifiers)...),
+		notifiers: config.Configurations.Notifiers,
+	}
+	m.start(m.workers)
+	return m
+}
+
+// start delivers the notifications queued for the workers in the background
+func (m *AlertManager) start(workers []*notifierWorker) {
+	for _, worker := range workers {
+		m.workerWg.Add(1)
+		go worker.run(&m.workerWg)
+	}
+}
+
+// Reconfigure recreates the notifiers defined in config when they have changed.
+// The previous notifiers stop after delivering the notifications already queued for them.
+func (m *AlertManager) Reconfigure(config *cfg.Config) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if reflect.DeepEqual(config.Configurations.Notifiers, m.notifiers) {
+		return
+	}
+	LogToConsole(InfoLevel, InfoEvent, "Notifier configuration changed, recreating notifiers")
+
+	for _, worker := range m.workers[1:] {
+		close(worker.queue)
+	}
+	workers := newNotifierWorkers(config.Configurations.Notifiers)
+	m.start(workers)
+	m.workers = append([]*notifierWorker{m.workers[0]}, workers...)
+	m.notifiers = config.Configurations.Notifiers
+}
+
+// alertInstance identifies this Trawler instance in alerts, preferring the Kubernetes pod name
+func alertInstance() string {
+	if podName := os.Getenv("POD_NAME"); podName != "" {
+		return podName
+	}
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		return "unknown"
+	}
+	return hostname
+}
+
+// alertKey returns the key identifying the condition of a report.
+// Reports without an explicit key are identified by their context.
+func alertKey(report ErrorReport) string {
+	if report.Key != "" {
+		return report.Key
+	}
+	return report.Context
+}
+
+// Handle processes a report, sending a firing alert for a new condition and a resolved alert when it clears
+func (m *AlertManager) Handle(report ErrorReport) {
+	key := alertKey(report)
+	now := time.Now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	existing, isActive := m.active[key]
+	if report.Resolved {
+		if isActive {
+			m.resolve(key, existing, now)
+		}
+		return
+	}
+
+	fingerprint := Fingerprint(key, m.instance, report.Severity)
+	if isActive && existing.fingerprint == fingerprint {
+		// Same condition, it is repeated by CheckRepeats
+		existing.report = report
+		existing.lastSeen = now
+		return
+	}
+	if isActive {
+		// The severity of the condition changed, so the alert at the previous severity is resolved
+		m.resolve(key, existing, now)
+	}
+
+	alert := &activeAlert{
+		fingerprint: fingerprint,
+		report:      report,
+		startsAt:    now,
+		lastSeen:    now,
+		lastSent:    now,
+	}
+	m.active[key] = alert
+	m.enqueue(alert, AlertStatusFiring, time.Time{})
+}
+
+// CheckRepeats sends active alerts again when the repeat interval has passed,
+// and resolves alerts whose condition has not been reported within the resolve timeout
+func (m *AlertManager) CheckRepeats() {
+	now := time.Now()
+	alarmathan := cfg.Current().Configurations.Alarmathan
+
+	repeatInterval := time.Duration(alarmathan.RepeatIntervalMinutes) * time.Minute
+	if repeatInterval <= 0 {
+		repeatInterval = defaultRepeatIntervalMinutes * time.Minute
+	}
+	resolveTimeout := time.Duration(alarmathan.ResolveTimeoutMinutes) * time.Minute
+	if resolveTimeout <= 0 {
+		resolveTimeout = defaultResolveTimeoutMinutes * time.Minute
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for key, alert := range m.active {
+		switch {
+		case now.Sub(alert.lastSeen) >= resolveTimeout:
+			m.resolve(key, alert, now)
+		case now.Sub(alert.lastSent) >= repeatInterval:
+			alert.lastSent = now
+			m.enqueue(alert, AlertStatusFiring, time.Time{})
+		}
+	}
+}
+
+// resolve sends a resolved alert and removes it from the active alerts. Must be called with the lock held.
+func (m *AlertManager) resolve(key string, alert *activeAlert, now time.Time) {
+	delete(m.active, key)
+	m.enqueue(alert, AlertStatusResolved, now)
+	LogToConsole(InfoLevel, InfoEvent, fmt.Sprintf("Alert resolved: %s", alert.report.Context))
+}
+
+// enqueue queues a notification for every notifier that accepts it, without blocking the processing of reports
+func (m *AlertManager) enqueue(alert *activeAlert, status string, endsAt time.Time) {
+	notification := newNotification(alert, status, m.instance, endsAt)
+	for _, worker := range m.workers {
+		if !worker.accepts(notification) {
+			continue
+		}
+		select {
+		case worker.queue <- notification:
+		default:
+			LogToConsole(ErrorLevel, ErrorEvent, fmt.Sprintf("[%s] Notification queue is full, dropping %s alert: %s", worker.notifier.Name(), status, alert.report.Context))
+		}
+	}
+}
+
+// Close stops accepting alerts and waits until the queued notifications have been delivered
+func (m *AlertManager) Close() {
+	for _, worker := range m.workers {
+		close(worker.queue)
+	}
+	m.workerWg.Wait()
+}
@@ -0,0 +1,52 @@
+package logging
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	cfg "trawler/pkg/config"
+)
+
+// newCountingServer returns a webhook endpoint and the number of notifications it received
+func newCountingServer(t *testing.T) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var received atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received.Add(1)
+	}))
+	t.Cleanup(server.Close)
+	return server, &received
+}
+
+// webhookConfig returns a config with a single webhook notifier
+func webhookConfig(url string) *cfg.Config {
+	config := &cfg.Config{}
+	config.Configurations.Notifiers = []cfg.Notifier{{Name: "hook", Type: NotifierTypeWebhook, URL: url}}
+	return config
+}
+
+func TestReconfigureReplacesNotifiers(t *testing.T) {
+	cfg.SetCurrent(&cfg.Config{})
+	previous, previousReceived := newCountingServer(t)
+	replacement, replacementReceived := newCountingServer(t)
+
+	m := NewAlertManager(webhookConfig(previous.URL))
+	m.Handle(ErrorReport{Err: errors.New("failed"), Context: "first", Severity: SeverityWarning, Key: "first"})
+
+	// The same notifiers are kept
+	workers := m.workers
+	m.Reconfigure(webhookConfig(previous.URL))
+	if m.workers[1] != workers[1] {
+		t.Error("unchanged notifiers were recreated")
+	}
+
+	m.Reconfigure(webhookConfig(replacement.URL))
+	m.Handle(ErrorReport{Err: errors.New("failed"), Context: "second", Severity: SeverityWarning, Key: "second"})
+	m.Close()
+
+	if previousReceived.Load() != 1 || replacementReceived.Load() != 1 {
+		t.Errorf("previous notifier received %d, replacement %d, want 1 each", previousReceived.Load(), replacementReceived.Load())
+	}
+}
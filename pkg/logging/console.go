@@ -1,8 +1,16 @@
 package logging
 
 import (
-	"log"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
 	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 type LogLevel string
@@ -23,23 +31,212 @@ const (
 	DebugEvent   EventType = "DEBUG"
 )
 
+// Output formats supported in the config
+const (
+	OutputFormatPretty = "pretty"
+	OutputFormatJSON   = "json"
+)
+
+// Field is a structured key-value pair attached to a log entry
+type Field = slog.Attr
+
+// Keys of the structured fields, so that log queries can rely on them
+const (
+	FieldCRL      = "crl"
+	FieldURL      = "url"
+	FieldBackend  = "backend"
+	FieldHash     = "hash"
+	FieldDuration = "duration"
+	FieldError    = "error"
+	FieldDiff     = "diff"
+)
+
+// CRL returns a field with the name of a CRL
+func CRL(name string) Field { return slog.String(FieldCRL, name) }
+
+// URL returns a field with the URL a CRL is retrieved from
+func URL(url string) Field { return slog.String(FieldURL, url) }
+
+// Backend returns a field with the name of a storage backend
+func Backend(name string) Field { return slog.String(FieldBackend, name) }
+
+// Hash returns a field with the hash of a CRL
+func Hash(hash string) Field { return slog.String(FieldHash, hash) }
+
+// Duration returns a field with the duration of an operation in seconds
+func Duration(duration time.Duration) Field { return slog.Float64(FieldDuration, duration.Seconds()) }
+
+// Diff returns a field with the changes between two versions of a CRL.
+// The JSON format logs the full diff, the pretty format its summary.
+func Diff(diff fmt.Stringer) Field { return slog.Any(FieldDiff, diff) }
+
+// Err returns a field with an error
+func Err(err error) Field {
+	if err == nil {
+		return slog.String(FieldError, "")
+	}
+	return slog.String(FieldError, err.Error())
+}
+
+var (
+	level  = new(slog.LevelVar)
+	logger atomic.Pointer[slog.Logger]
+)
+
+func init() {
+	Configure(string(InfoLevel), OutputFormatPretty)
+}
+
+// Configure sets the log level and output format, and can be called again when the config is refreshed.
+// Logs are written to stderr, like the standard logger used before.
+// Debug logging can always be forced with the TRAWLER_DEBUG environment variable.
+func Configure(logLevel string, outputFormat string) {
+	parsedLevel, levelErr := parseLevel(logLevel)
+	if os.Getenv("TRAWLER_DEBUG") == "true" {
+		parsedLevel = slog.LevelDebug
+	}
+	level.Set(parsedLevel)
+
+	var handler slog.Handler
+	var formatErr error
+	switch strings.ToLower(outputFormat) {
+	case OutputFormatJSON:
+		handler = slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: level, ReplaceAttr: replaceLevelName})
+	case OutputFormatPretty, "":
+		handler = newPrettyHandler(os.Stderr, level)
+	default:
+		handler = newPrettyHandler(os.Stderr, level)
+		formatErr = fmt.Errorf("unknown output format %q, using %q", outputFormat, OutputFormatPretty)
+	}
+	logger.Store(slog.New(handler))
+
+	if levelErr != nil {
+		LogToConsole(WarningLevel, WarningEvent, levelErr.Error())
+	}
+	if formatErr != nil {
+		LogToConsole(WarningLevel, WarningEvent, formatErr.Error())
+	}
+}
+
+func parseLevel(logLevel string) (slog.Level, error) {
+	switch strings.ToUpper(logLevel) {
+	case string(DebugLevel):
+		return slog.LevelDebug, nil
+	case string(InfoLevel), "":
+		return slog.LevelInfo, nil
+	case string(WarningLevel), "WARN":
+		return slog.LevelWarn, nil
+	case string(ErrorLevel):
+		return slog.LevelError, nil
+	default:
+		return slog.LevelInfo, fmt.Errorf("unknown log level %q, using %q", logLevel, strings.ToLower(string(InfoLevel)))
+	}
+}
+
 func LogToConsole(logLevel LogLevel, eventType EventType, message string) {
-	// Create a formatted log message
-	logMessage := "[" + string(eventType) + "] " + message
+	LogFields(logLevel, eventType, message)
+}
 
-	// Log the message based on the log level
+// LogFields logs a message with structured fields, e.g. logging.CRL(name) or logging.Backend(name)
+func LogFields(logLevel LogLevel, eventType EventType, message string, fields ...Field) {
+	var slogLevel slog.Level
 	switch logLevel {
 	case InfoLevel:
-		log.Println(logMessage)
+		slogLevel = slog.LevelInfo
 	case WarningLevel:
-		log.Println(logMessage)
+		slogLevel = slog.LevelWarn
 	case ErrorLevel:
-		log.Println(logMessage)
+		slogLevel = slog.LevelError
 	case DebugLevel:
-		if os.Getenv("TRAWLER_DEBUG") == "true" {
-			log.Println(logMessage)
-		}
+		slogLevel = slog.LevelDebug
 	default:
-		log.Println("[" + "UNKNOWN" + "] [" + string(eventType) + "] " + message)
+		slogLevel = slog.LevelInfo
+	}
+	// The event is only logged when it adds information to the level
+	if string(eventType) != string(logLevel) {
+		fields = append(fields, slog.String("event", string(eventType)))
+	}
+	logger.Load().LogAttrs(context.Background(), slogLevel, message, fields...)
+}
+
+// levelName returns the level names used by LogLevel instead of the slog names
+func levelName(slogLevel slog.Level) string {
+	switch {
+	case slogLevel < slog.LevelInfo:
+		return string(DebugLevel)
+	case slogLevel < slog.LevelWarn:
+		return string(InfoLevel)
+	case slogLevel < slog.LevelError:
+		return string(WarningLevel)
+	default:
+		return string(ErrorLevel)
+	}
+}
+
+func replaceLevelName(groups []string, attr slog.Attr) slog.Attr {
+	if len(groups) == 0 && attr.Key == slog.LevelKey {
+		if slogLevel, ok := attr.Value.Any().(slog.Level); ok {
+			return slog.String(slog.LevelKey, levelName(slogLevel))
+		}
+	}
+	return attr
+}
+
+// prettyHandler writes human readable lines in the format "2006/01/02 15:04:05 [LEVEL] message key=value"
+type prettyHandler struct {
+	mu    *sync.Mutex
+	out   io.Writer
+	level slog.Leveler
+	attrs []slog.Attr
+}
+
+func newPrettyHandler(out io.Writer, level slog.Leveler) *prettyHandler {
+	return &prettyHandler{mu: &sync.Mutex{}, out: out, level: level}
+}
+
+func (h *prettyHandler) Enabled(_ context.Context, slogLevel slog.Level) bool {
+	return slogLevel >= h.level.Level()
+}
+
+func (h *prettyHandler) Handle(_ context.Context, record slog.Record) error {
+	var line strings.Builder
+	line.WriteString(record.Time.Format("2006/01/02 15:04:05"))
+	line.WriteString(" [")
+	line.WriteString(levelName(record.Level))
+	line.WriteString("] ")
+	line.WriteString(record.Message)
+
+	writeAttr := func(attr slog.Attr) bool {
+		value := attr.Value.Resolve().String()
+		if value == "" || strings.ContainsAny(value, " \"=") {
+			value = strconv.Quote(value)
+		}
+		line.WriteString(" ")
+		line.WriteString(attr.Key)
+		line.WriteString("=")
+		line.WriteString(value)
+		return true
+	}
+	for _, attr := range h.attrs {
+		writeAttr(attr)
 	}
+	record.Attrs(writeAttr)
+	line.WriteString("\n")
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := io.WriteString(h.out, line.String())
+	return err
+}
+
+func (h *prettyHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	combined := make([]slog.Attr, 0, len(h.attrs)+len(attrs))
+	combined = append(combined, h.attrs...)
+	combined = append(combined, attrs...)
+	return &prettyHandler{mu: h.mu, out: h.out, level: h.level, attrs: combined}
+}
+
+// WithGroup is not used by Trawler, so groups are flattened
+func (h *prettyHandler) WithGroup(_ string) slog.Handler {
+	return h
 }
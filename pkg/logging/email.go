@@ -0,0 +1,127 @@
+package logging
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	cfg "trawler/pkg/config"
+)
+
+// EmailNotifier sends notifications as plain text email over SMTP
+type EmailNotifier struct {
+	name   string
+	config cfg.SMTPConfig
+}
+
+// NewEmailNotifier creates an email notifier from config
+func NewEmailNotifier(notifierConfig cfg.Notifier) (*EmailNotifier, error) {
+	smtpConfig := notifierConfig.SMTP
+	if smtpConfig.Host == "" || smtpConfig.From == "" || len(smtpConfig.To) == 0 {
+		return nil, fmt.Errorf("smtp host, from and to are required for email notifiers")
+	}
+	if smtpConfig.Port == 0 {
+		smtpConfig.Port = 587
+		if smtpConfig.TLS {
+			smtpConfig.Port = 465
+		}
+	}
+	if smtpConfig.Password == "" {
+		smtpConfig.Password = os.Getenv("SMTP_PASSWORD")
+	}
+	return &EmailNotifier{name: notifierConfig.Name, config: smtpConfig}, nil
+}
+
+func (n *EmailNotifier) Name() string {
+	return n.name
+}
+
+func (n *EmailNotifier) Notify(ctx context.Context, notification Notification) error {
+	message := n.message(notification)
+	address := net.JoinHostPort(n.config.Host, strconv.Itoa(n.config.Port))
+
+	var auth smtp.Auth
+	if n.config.Username != "" {
+		auth = smtp.PlainAuth("", n.config.Username, n.config.Password, n.config.Host)
+	}
+
+	if !n.config.TLS {
+		// SendMail upgrades the connection with STARTTLS when the server offers it
+		return runWithContext(ctx, func() error {
+			return smtp.SendMail(address, auth, n.config.From, n.config.To, message)
+		})
+	}
+
+	dialer := &tls.Dialer{Config: &tls.Config{ServerName: n.config.Host}}
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, n.config.Host)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	if auth != nil {
+		if err := client.Auth(auth); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(n.config.From); err != nil {
+		return err
+	}
+	for _, recipient := range n.config.To {
+		if err := client.Rcpt(recipient); err != nil {
+			return err
+		}
+	}
+	writer, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := writer.Write(message); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// message formats the notification as an RFC 5322 message
+func (n *EmailNotifier) message(notification Notification) []byte {
+	var message strings.Builder
+	fmt.Fprintf(&message, "From: %s\r\n", n.config.From)
+	fmt.Fprintf(&message, "To: %s\r\n", strings.Join(n.config.To, ", "))
+	// Severities contain non-ASCII characters, so the subject is encoded
+	fmt.Fprintf(&message, "Subject: %s\r\n", mime.QEncoding.Encode("UTF-8", notification.Title()))
+	fmt.Fprintf(&message, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	message.WriteString("MIME-Version: 1.0\r\n")
+	message.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	message.WriteString("\r\n")
+	message.WriteString(strings.ReplaceAll(notification.Text(), "\n", "\r\n"))
+	return []byte(message.String())
+}
+
+// runWithContext runs a blocking function, returning early if the context is done
+func runWithContext(ctx context.Context, run func() error) error {
+	done := make(chan error, 1)
+	go func() { done <- run() }()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -2,24 +2,54 @@ package logging
 
 import (
 	"fmt"
+	"time"
 	cfg "trawler/pkg/config"
 )
 
+// How often active alerts are checked for repeats and resolve timeouts, and the notifiers for config changes
+const alertCheckInterval = 1 * time.Minute
+
 // handleErrors allows for easy handling of errors throughout the program
+// It returns when errChannel is closed, after the queued alerts have been delivered
 func HandleErrors(errChannel <-chan ErrorReport, config *cfg.Config) {
-	for errReport := range errChannel {
-		// Log to console
-		LogToConsole(ErrorLevel, ErrorEvent,
-			fmt.Sprintf("%s: %v", errReport.Context, errReport.Err))
+	alertManager := NewAlertManager(config)
+	defer alertManager.Close()
+
+	ticker := time.NewTicker(alertCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case errReport, open := <-errChannel:
+			if !open {
+				return
+			}
+			// Log to console, resolved reports are only of interest to the alert manager
+			if !errReport.Resolved {
+				level, event := severityLogLevel(errReport.Severity)
+				LogFields(level, event,
+					fmt.Sprintf("%s: %v", errReport.Context, errReport.Err),
+					append(errReport.Fields, Err(errReport.Err))...)
+			}
 
-		// Send to external endpoint
-		alarm := GenerateAlarm(*config,
-			errReport.Context,
-			errReport.Criticality,
-			errReport.Severity,
-			"ThisInstanceAsItWere",
-			errReport.Err.Error())
+			// Send to external endpoint
+			alertManager.Handle(errReport)
+		case <-ticker.C:
+			alertManager.Reconfigure(cfg.Current())
+			alertManager.CheckRepeats()
+		}
+	}
+}
 
-		SendToWebhook(config.Configurations.Alarmathan.WebhookURL, *alarm)
+// severityLogLevel returns the level a report of the given severity is logged at.
+// Reports without a known severity are logged as errors, so that they are not overlooked.
+func severityLogLevel(severity SeverityLevel) (LogLevel, EventType) {
+	switch severity {
+	case SeverityLow, SeverityNormal:
+		return InfoLevel, InfoEvent
+	case SeverityWarning:
+		return WarningLevel, WarningEvent
+	default:
+		return ErrorLevel, ErrorEvent
 	}
 }
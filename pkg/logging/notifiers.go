@@ -0,0 +1,293 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+	cfg "trawler/pkg/config"
+)
+
+// Notifier types supported in the config
+const (
+	NotifierTypeWebhook = "webhook"
+	NotifierTypeSlack   = "slack"
+	NotifierTypeTeams   = "teams"
+	NotifierTypeEmail   = "email"
+)
+
+const (
+	defaultNotifierMaxRetries = 3
+	notifyTimeout             = 30 * time.Second
+)
+
+// Notification is an alert state change delivered to notifiers
+type Notification struct {
+	Status      string // firing or resolved
+	Summary     string // Context of the report
+	Description string // Error of the report
+	Details     string // Longer description of the report, if any
+	Severity    SeverityLevel
+	Criticality CriticalityLevel
+	Key         string
+	CRL         string            // Name of the CRL the alert is about, if any
+	Fields      map[string]string // Structured fields of the report, e.g. backend and url
+	Fingerprint string
+	Instance    string
+	StartsAt    time.Time
+	EndsAt      time.Time // Zero while firing
+}
+
+// Notifier sends notifications to a channel such as a webhook, chat or email
+type Notifier interface {
+	Name() string
+	Notify(ctx context.Context, notification Notification) error
+}
+
+// newNotification creates the notification for an active alert
+func newNotification(alert *activeAlert, status string, instance string, endsAt time.Time) Notification {
+	report := alert.report
+	notification := Notification{
+		Status:      status,
+		Summary:     report.Context,
+		Severity:    report.Severity,
+		Criticality: report.Criticality,
+		Key:         alertKey(report),
+		Details:     report.Details,
+		Fields:      make(map[string]string),
+		Fingerprint: alert.fingerprint,
+		Instance:    instance,
+		StartsAt:    alert.startsAt,
+		EndsAt:      endsAt,
+	}
+	if report.Err != nil {
+		notification.Description = report.Err.Error()
+	}
+	for _, field := range report.Fields {
+		notification.Fields[field.Key] = field.Value.String()
+	}
+	notification.CRL = notification.Fields[FieldCRL]
+	return notification
+}
+
+// Title returns a one-line summary of the notification, used by chat and email notifiers
+func (n Notification) Title() string {
+	return fmt.Sprintf("[%s] %s: %s", strings.ToUpper(n.Status), n.Severity, n.Summary)
+}
+
+// Text returns a plain text description of the notification, used by chat and email notifiers
+func (n Notification) Text() string {
+	var text strings.Builder
+	if n.Description != "" {
+		fmt.Fprintf(&text, "%s\n\n", n.Description)
+	}
+	fmt.Fprintf(&text, "Severity: %s\nCriticality: %s\nInstance: %s\n", n.Severity, n.Criticality, n.Instance)
+	if n.CRL != "" {
+		fmt.Fprintf(&text, "CRL: %s\n", n.CRL)
+	}
+	fmt.Fprintf(&text, "Started: %s\n", n.StartsAt.UTC().Format(time.RFC3339))
+	if n.Status == AlertStatusResolved {
+		fmt.Fprintf(&text, "Resolved: %s\n", n.EndsAt.UTC().Format(time.RFC3339))
+	}
+	if n.Details != "" {
+		fmt.Fprintf(&text, "\n%s\n", strings.TrimRight(n.Details, "\n"))
+	}
+	return text.String()
+}
+
+// severityRank orders severities from low to critical
+func severityRank(severity SeverityLevel) int {
+	switch severity {
+	case SeverityLow:
+		return 1
+	case SeverityNormal:
+		return 2
+	case SeverityWarning:
+		return 3
+	case SeverityCritical:
+		return 4
+	default:
+		return 0
+	}
+}
+
+// ParseSeverity parses a severity from config, either by its English name or its value
+func ParseSeverity(severity string) (SeverityLevel, error) {
+	switch strings.ToLower(severity) {
+	case "", "low", strings.ToLower(string(SeverityLow)):
+		return SeverityLow, nil
+	case "normal", "medium", strings.ToLower(string(SeverityNormal)):
+		return SeverityNormal, nil
+	case "warning", "high", strings.ToLower(string(SeverityWarning)):
+		return SeverityWarning, nil
+	case "critical", strings.ToLower(string(SeverityCritical)):
+		return SeverityCritical, nil
+	default:
+		return SeverityLow, fmt.Errorf("unknown severity %q", severity)
+	}
+}
+
+// notificationRoute is a parsed routing rule of a notifier
+type notificationRoute struct {
+	crls        map[string]bool
+	keyPrefixes []string
+	minSeverity SeverityLevel
+}
+
+func (r notificationRoute) matches(notification Notification) bool {
+	if severityRank(notification.Severity) < severityRank(r.minSeverity) {
+		return false
+	}
+	if len(r.crls) > 0 && !r.crls[notification.CRL] {
+		return false
+	}
+	if len(r.keyPrefixes) > 0 {
+		for _, prefix := range r.keyPrefixes {
+			if strings.HasPrefix(notification.Key, prefix) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// notifierWorker delivers notifications to a single notifier in the background,
+// so that a slow or failing channel does not delay the others
+type notifierWorker struct {
+	notifier     Notifier
+	routes       []notificationRoute
+	sendResolved bool
+	maxRetries   func() int
+	queue        chan Notification
+}
+
+// accepts reports whether the notification should be sent by this worker
+func (w *notifierWorker) accepts(notification Notification) bool {
+	if notification.Status == AlertStatusResolved && !w.sendResolved {
+		return false
+	}
+	for _, route := range w.routes {
+		if route.matches(notification) {
+			return true
+		}
+	}
+	return false
+}
+
+// run sends queued notifications in order, retrying failed ones with exponential backoff
+func (w *notifierWorker) run(workerWg *sync.WaitGroup) {
+	defer workerWg.Done()
+	for notification := range w.queue {
+		maxRetries := w.maxRetries()
+		backoff := alertInitialBackoff
+		for attempt := 0; ; attempt++ {
+			ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+			err := w.notifier.Notify(ctx, notification)
+			cancel()
+			if err == nil {
+				break
+			}
+			if attempt >= maxRetries {
+				LogFields(ErrorLevel, ErrorEvent, fmt.Sprintf("[%s] Giving up sending notification after %d attempts: %s", w.notifier.Name(), attempt+1, notification.Summary), Err(err))
+				break
+			}
+			LogFields(WarningLevel, WarningEvent, fmt.Sprintf("[%s] Error sending notification, retrying in %s", w.notifier.Name(), backoff), Err(err))
+			time.Sleep(backoff)
+			backoff *= 2
+			if backoff > alertMaxBackoff {
+				backoff = alertMaxBackoff
+			}
+		}
+	}
+}
+
+// newAlarmathanWorker creates the worker of the Alarmathan notifier, which reads its settings from the configuration in effect
+func newAlarmathanWorker() *notifierWorker {
+	return &notifierWorker{
+		notifier:     &AlarmathanNotifier{},
+		routes:       []notificationRoute{{minSeverity: SeverityLow}},
+		sendResolved: true,
+		maxRetries: func() int {
+			if maxRetries := cfg.Current().Configurations.Alarmathan.MaxRetries; maxRetries > 0 {
+				return maxRetries
+			}
+			return defaultAlertMaxRetries
+		},
+		queue: make(chan Notification, alertQueueSize),
+	}
+}
+
+// newNotifierWorkers creates the notifiers defined in config.
+// Invalid notifiers are logged and skipped, so they do not prevent the others from working.
+func newNotifierWorkers(notifiers []cfg.Notifier) []*notifierWorker {
+	var workers []*notifierWorker
+	for _, notifierConfig := range notifiers {
+		worker, err := newNotifierWorker(notifierConfig)
+		if err != nil {
+			LogToConsole(ErrorLevel, ErrorEvent, fmt.Sprintf("Invalid notifier %q: %v", notifierConfig.Name, err))
+			continue
+		}
+		LogToConsole(InfoLevel, InfoEvent, fmt.Sprintf("Notifier %s (%s) enabled", notifierConfig.Name, notifierConfig.Type))
+		workers = append(workers, worker)
+	}
+	return workers
+}
+
+func newNotifierWorker(notifierConfig cfg.Notifier) (*notifierWorker, error) {
+	var notifier Notifier
+	var err error
+	switch notifierConfig.Type {
+	case NotifierTypeWebhook:
+		notifier, err = NewWebhookNotifier(notifierConfig)
+	case NotifierTypeSlack:
+		notifier, err = NewSlackNotifier(notifierConfig)
+	case NotifierTypeTeams:
+		notifier, err = NewTeamsNotifier(notifierConfig)
+	case NotifierTypeEmail:
+		notifier, err = NewEmailNotifier(notifierConfig)
+	default:
+		err = fmt.Errorf("unsupported notifier type: %q", notifierConfig.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	minSeverity, err := ParseSeverity(notifierConfig.MinSeverity)
+	if err != nil {
+		return nil, err
+	}
+	var routes []notificationRoute
+	for _, routeConfig := range notifierConfig.Routes {
+		route := notificationRoute{keyPrefixes: routeConfig.KeyPrefixes, minSeverity: minSeverity}
+		if routeConfig.MinSeverity != "" {
+			route.minSeverity, err = ParseSeverity(routeConfig.MinSeverity)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if len(routeConfig.CRLs) > 0 {
+			route.crls = make(map[string]bool)
+			for _, crlName := range routeConfig.CRLs {
+				route.crls[crlName] = true
+			}
+		}
+		routes = append(routes, route)
+	}
+	if len(routes) == 0 {
+		routes = []notificationRoute{{minSeverity: minSeverity}}
+	}
+
+	maxRetries := notifierConfig.MaxRetries
+	if maxRetries <= 0 {
+		maxRetries = defaultNotifierMaxRetries
+	}
+	return &notifierWorker{
+		notifier:     notifier,
+		routes:       routes,
+		sendResolved: notifierConfig.SendResolved,
+		maxRetries:   func() int { return maxRetries },
+		queue:        make(chan Notification, alertQueueSize),
+	}, nil
+}
@@ -10,9 +10,20 @@ type crlTimeStamps struct {
 	NextCRLPublish time.Time `json:"nextPublish"`
 }
 
+// ErrorReport describes a problem to log and alert on.
+// Reports with a Key can be resolved by sending a report with the same Key and Resolved set once the problem clears.
 type ErrorReport struct {
 	Err         error
 	Context     string
 	Severity    SeverityLevel
 	Criticality CriticalityLevel
+	Fields      []Field // Structured fields logged with the error, e.g. the CRL name
+	Key         string  // Identifies the condition across reports, e.g. "process/<crl name>"
+	Details     string  // Longer description attached to notifications, e.g. the changes to a CRL
+	Resolved    bool    // The condition identified by Key has cleared
+}
+
+// ResolvedReport returns a report that resolves the alert for the condition identified by key
+func ResolvedReport(key string) ErrorReport {
+	return ErrorReport{Key: key, Resolved: true}
 }
@@ -0,0 +1,155 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+	cfg "trawler/pkg/config"
+)
+
+// defaultWebhookTemplate is used by generic webhooks without a template
+const defaultWebhookTemplate = `{"status":{{json .Status}},"summary":{{json .Summary}},"description":{{json .Description}},"details":{{json .Details}},` +
+	`"severity":{{json .Severity}},"criticality":{{json .Criticality}},"key":{{json .Key}},"crl":{{json .CRL}},` +
+	`"instance":{{json .Instance}},"fingerprint":{{json .Fingerprint}},"startsAt":{{json .StartsAt}},"endsAt":{{json .EndsAt}}}`
+
+// templateFuncs are available in webhook templates
+var templateFuncs = template.FuncMap{
+	// json encodes a value, so that templates can produce valid JSON from any field
+	"json": func(value interface{}) (string, error) {
+		if t, ok := value.(time.Time); ok && t.IsZero() {
+			return "null", nil
+		}
+		data, err := json.Marshal(value)
+		return string(data), err
+	},
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+}
+
+// WebhookNotifier posts notifications rendered with a Go template to a generic webhook
+type WebhookNotifier struct {
+	name        string
+	url         string
+	contentType string
+	headers     map[string]string
+	template    *template.Template
+}
+
+// NewWebhookNotifier creates a generic webhook notifier from config
+func NewWebhookNotifier(notifierConfig cfg.Notifier) (*WebhookNotifier, error) {
+	if notifierConfig.URL == "" {
+		return nil, fmt.Errorf("url is required for webhook notifiers")
+	}
+	templateText := notifierConfig.Template
+	if templateText == "" {
+		templateText = defaultWebhookTemplate
+	}
+	bodyTemplate, err := template.New(notifierConfig.Name).Funcs(templateFuncs).Parse(templateText)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing template: %w", err)
+	}
+	contentType := notifierConfig.ContentType
+	if contentType == "" {
+		contentType = "application/json"
+	}
+	return &WebhookNotifier{
+		name:        notifierConfig.Name,
+		url:         notifierConfig.URL,
+		contentType: contentType,
+		headers:     notifierConfig.Headers,
+		template:    bodyTemplate,
+	}, nil
+}
+
+func (n *WebhookNotifier) Name() string {
+	return n.name
+}
+
+func (n *WebhookNotifier) Notify(ctx context.Context, notification Notification) error {
+	var body bytes.Buffer
+	err := n.template.Execute(&body, notification)
+	if err != nil {
+		return fmt.Errorf("error rendering template: %w", err)
+	}
+	return postToWebhook(ctx, n.url, n.contentType, n.headers, body.Bytes())
+}
+
+// SlackNotifier posts notifications to a Slack incoming webhook
+type SlackNotifier struct {
+	name string
+	url  string
+}
+
+// NewSlackNotifier creates a Slack notifier from config
+func NewSlackNotifier(notifierConfig cfg.Notifier) (*SlackNotifier, error) {
+	if notifierConfig.URL == "" {
+		return nil, fmt.Errorf("url is required for Slack notifiers")
+	}
+	return &SlackNotifier{name: notifierConfig.Name, url: notifierConfig.URL}, nil
+}
+
+func (n *SlackNotifier) Name() string {
+	return n.name
+}
+
+func (n *SlackNotifier) Notify(ctx context.Context, notification Notification) error {
+	message := map[string]string{
+		"text": fmt.Sprintf("%s %s\n%s", notificationEmoji(notification), notification.Title(), notification.Text()),
+	}
+	return sendJSONToWebhook(ctx, n.url, message)
+}
+
+// TeamsNotifier posts notifications to a Microsoft Teams incoming webhook as a message card
+type TeamsNotifier struct {
+	name string
+	url  string
+}
+
+// NewTeamsNotifier creates a Teams notifier from config
+func NewTeamsNotifier(notifierConfig cfg.Notifier) (*TeamsNotifier, error) {
+	if notifierConfig.URL == "" {
+		return nil, fmt.Errorf("url is required for Teams notifiers")
+	}
+	return &TeamsNotifier{name: notifierConfig.Name, url: notifierConfig.URL}, nil
+}
+
+func (n *TeamsNotifier) Name() string {
+	return n.name
+}
+
+func (n *TeamsNotifier) Notify(ctx context.Context, notification Notification) error {
+	card := map[string]string{
+		"@type":      "MessageCard",
+		"@context":   "https://schema.org/extensions",
+		"summary":    notification.Title(),
+		"title":      notification.Title(),
+		"themeColor": notificationColor(notification),
+		// Teams renders the text as markdown, where single line breaks are ignored
+		"text": strings.ReplaceAll(notification.Text(), "\n", "\n\n"),
+	}
+	return sendJSONToWebhook(ctx, n.url, card)
+}
+
+func notificationEmoji(notification Notification) string {
+	if notification.Status == AlertStatusResolved {
+		return ":white_check_mark:"
+	}
+	if notification.Severity == SeverityCritical {
+		return ":rotating_light:"
+	}
+	return ":warning:"
+}
+
+func notificationColor(notification Notification) string {
+	if notification.Status == AlertStatusResolved {
+		return "2EB886"
+	}
+	if notification.Severity == SeverityCritical {
+		return "D00000"
+	}
+	return "FFA500"
+}
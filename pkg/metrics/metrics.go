@@ -0,0 +1,233 @@
+package metrics
+
+import (
+	"crypto/x509"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Outcomes used as label values for the pipeline metrics
+const (
+	OutcomeSuccess     = "success"
+	OutcomeError       = "error"
+	OutcomeNotModified = "not_modified"
+	OutcomeInvalid     = "invalid"
+	OutcomeUnchanged   = "unchanged"
+	OutcomeRejected    = "rejected"
+)
+
+// Statuses reported per component, matching the constants in pkg/health
+var componentStatuses = []string{"ok", "degraded", "unhealthy", "unknown"}
+
+var registry = prometheus.NewRegistry()
+
+var (
+	fetchTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "trawler_crl_fetch_total",
+		Help: "Number of CRL fetches by outcome.",
+	}, []string{"crl", "outcome"})
+	fetchDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "trawler_crl_fetch_duration_seconds",
+		Help:    "Time spent fetching CRLs by outcome.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"outcome"})
+	validateTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "trawler_crl_validate_total",
+		Help: "Number of CRL validations by outcome.",
+	}, []string{"crl", "outcome"})
+	validateDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "trawler_crl_validate_duration_seconds",
+		Help:    "Time spent validating CRLs by outcome.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"outcome"})
+	publishTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "trawler_crl_publish_total",
+		Help: "Number of CRL publications per storage backend by outcome.",
+	}, []string{"crl", "backend", "outcome"})
+	publishDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "trawler_crl_publish_duration_seconds",
+		Help:    "Time spent publishing CRLs per storage backend by outcome.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"backend", "outcome"})
+	lastSuccessfulCycle = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "trawler_last_successful_cycle_timestamp_seconds",
+		Help: "Time of the last processing cycle in which every CRL was processed without errors.",
+	})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		fetchTotal, fetchDuration,
+		validateTotal, validateDuration,
+		publishTotal, publishDuration,
+		lastSuccessfulCycle,
+		crlInfo,
+	)
+}
+
+// Handler returns the HTTP handler for the /metrics endpoint
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// ObserveFetch records the outcome and duration of a CRL fetch
+func ObserveFetch(crlName string, outcome string, duration time.Duration) {
+	crlInfo.observe(crlName)
+	fetchTotal.WithLabelValues(crlName, outcome).Inc()
+	fetchDuration.WithLabelValues(outcome).Observe(duration.Seconds())
+}
+
+// ObserveValidation records the outcome and duration of a CRL validation
+func ObserveValidation(crlName string, outcome string, duration time.Duration) {
+	crlInfo.observe(crlName)
+	validateTotal.WithLabelValues(crlName, outcome).Inc()
+	validateDuration.WithLabelValues(outcome).Observe(duration.Seconds())
+}
+
+// ObservePublish records the outcome and duration of publishing a CRL to a storage backend
+func ObservePublish(crlName string, backend string, outcome string, duration time.Duration) {
+	crlInfo.observe(crlName)
+	publishTotal.WithLabelValues(crlName, backend, outcome).Inc()
+	publishDuration.WithLabelValues(backend, outcome).Observe(duration.Seconds())
+}
+
+// SetLastSuccessfulCycle records the time of the last cycle without errors
+func SetLastSuccessfulCycle(timestamp time.Time) {
+	lastSuccessfulCycle.Set(float64(timestamp.Unix()))
+}
+
+// ObserveCRL records the current state of a CRL, exposed as per-CRL gauges
+func ObserveCRL(crlName string, crlData *x509.RevocationList, nextCRLPublish time.Time, sizeBytes int) {
+	crlInfo.set(crlName, crlState{
+		thisUpdate:     crlData.ThisUpdate,
+		nextUpdate:     crlData.NextUpdate,
+		nextCRLPublish: nextCRLPublish,
+		revokedEntries: len(crlData.RevokedCertificateEntries),
+		number:         crlData.Number,
+		sizeBytes:      sizeBytes,
+	})
+}
+
+// RetainCRLs removes the series of every CRL not in crlNames, so CRLs removed from config or no longer
+// discovered do not keep reporting their last state
+func RetainCRLs(crlNames []string) {
+	retained := make(map[string]bool)
+	for _, crlName := range crlNames {
+		retained[crlName] = true
+	}
+
+	crlInfo.mu.Lock()
+	defer crlInfo.mu.Unlock()
+	for crlName := range crlInfo.observed {
+		if retained[crlName] {
+			continue
+		}
+		delete(crlInfo.observed, crlName)
+		delete(crlInfo.crls, crlName)
+		labels := prometheus.Labels{"crl": crlName}
+		fetchTotal.DeletePartialMatch(labels)
+		validateTotal.DeletePartialMatch(labels)
+		publishTotal.DeletePartialMatch(labels)
+	}
+}
+
+// SetComponentStatusFunc sets the function used to read component health statuses when metrics are scraped
+func SetComponentStatusFunc(statusFunc func() map[string]string) {
+	crlInfo.mu.Lock()
+	defer crlInfo.mu.Unlock()
+	crlInfo.componentStatus = statusFunc
+}
+
+// crlCollector exposes per-CRL gauges and component statuses, calculated when metrics are scraped
+type crlCollector struct {
+	mu              sync.Mutex
+	crls            map[string]crlState
+	observed        map[string]bool // Names of all CRLs with series, including the counters
+	componentStatus func() map[string]string
+}
+
+var crlInfo = &crlCollector{crls: make(map[string]crlState), observed: make(map[string]bool)}
+
+var (
+	crlThisUpdateDesc     = prometheus.NewDesc("trawler_crl_this_update_timestamp_seconds", "ThisUpdate of the CRL.", []string{"crl"}, nil)
+	crlNextUpdateDesc     = prometheus.NewDesc("trawler_crl_next_update_timestamp_seconds", "NextUpdate of the CRL.", []string{"crl"}, nil)
+	crlNextPublishDesc    = prometheus.NewDesc("trawler_crl_next_publish_timestamp_seconds", "NextCRLPublish (ADCS) of the CRL, if present.", []string{"crl"}, nil)
+	crlUntilExpiryDesc    = prometheus.NewDesc("trawler_crl_seconds_until_expiry", "Seconds until NextUpdate of the CRL, negative when expired.", []string{"crl"}, nil)
+	crlRevokedEntriesDesc = prometheus.NewDesc("trawler_crl_revoked_entries", "Number of revoked certificates listed in the CRL.", []string{"crl"}, nil)
+	crlNumberDesc         = prometheus.NewDesc("trawler_crl_number", "CRL Number of the CRL.", []string{"crl"}, nil)
+	crlSizeDesc           = prometheus.NewDesc("trawler_crl_size_bytes", "Size of the CRL in bytes.", []string{"crl"}, nil)
+	componentStatusDesc   = prometheus.NewDesc("trawler_component_status", "Health status of a component, 1 for the current status.", []string{"component", "status"}, nil)
+)
+
+type crlState struct {
+	thisUpdate     time.Time
+	nextUpdate     time.Time
+	nextCRLPublish time.Time
+	revokedEntries int
+	number         *big.Int
+	sizeBytes      int
+}
+
+func (c *crlCollector) set(crlName string, state crlState) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.crls[crlName] = state
+	c.observed[crlName] = true
+}
+
+func (c *crlCollector) observe(crlName string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.observed[crlName] = true
+}
+
+func (c *crlCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- crlThisUpdateDesc
+	ch <- crlNextUpdateDesc
+	ch <- crlNextPublishDesc
+	ch <- crlUntilExpiryDesc
+	ch <- crlRevokedEntriesDesc
+	ch <- crlNumberDesc
+	ch <- crlSizeDesc
+	ch <- componentStatusDesc
+}
+
+func (c *crlCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for crlName, state := range c.crls {
+		ch <- prometheus.MustNewConstMetric(crlThisUpdateDesc, prometheus.GaugeValue, float64(state.thisUpdate.Unix()), crlName)
+		ch <- prometheus.MustNewConstMetric(crlNextUpdateDesc, prometheus.GaugeValue, float64(state.nextUpdate.Unix()), crlName)
+		if !state.nextCRLPublish.IsZero() {
+			ch <- prometheus.MustNewConstMetric(crlNextPublishDesc, prometheus.GaugeValue, float64(state.nextCRLPublish.Unix()), crlName)
+		}
+		ch <- prometheus.MustNewConstMetric(crlUntilExpiryDesc, prometheus.GaugeValue, time.Until(state.nextUpdate).Seconds(), crlName)
+		ch <- prometheus.MustNewConstMetric(crlRevokedEntriesDesc, prometheus.GaugeValue, float64(state.revokedEntries), crlName)
+		if state.number != nil {
+			number, _ := new(big.Float).SetInt(state.number).Float64()
+			ch <- prometheus.MustNewConstMetric(crlNumberDesc, prometheus.GaugeValue, number, crlName)
+		}
+		ch <- prometheus.MustNewConstMetric(crlSizeDesc, prometheus.GaugeValue, float64(state.sizeBytes), crlName)
+	}
+
+	if c.componentStatus != nil {
+		for component, currentStatus := range c.componentStatus() {
+			for _, status := range componentStatuses {
+				value := 0.0
+				if status == currentStatus {
+					value = 1
+				}
+				ch <- prometheus.MustNewConstMetric(componentStatusDesc, prometheus.GaugeValue, value, component, status)
+			}
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package metrics
+
+import (
+	"crypto/x509"
+	"math/big"
+	"testing"
+	"time"
+)
+
+// crlSeries returns the number of series per CRL in the registry
+func crlSeries(t *testing.T) map[string]int {
+	t.Helper()
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	series := make(map[string]int)
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == "crl" {
+					series[label.GetValue()]++
+				}
+			}
+		}
+	}
+	return series
+}
+
+func TestRetainCRLsRemovesSeriesOfRemovedCRLs(t *testing.T) {
+	now := time.Now()
+	for _, crlName := range []string{"NHN Internal CA", "NHN Removed CA"} {
+		ObserveFetch(crlName, OutcomeSuccess, time.Second)
+		ObserveValidation(crlName, OutcomeSuccess, time.Second)
+		ObservePublish(crlName, "Local", OutcomeSuccess, time.Second)
+		ObserveCRL(crlName, &x509.RevocationList{Number: big.NewInt(1), ThisUpdate: now, NextUpdate: now.Add(time.Hour)}, time.Time{}, 100)
+	}
+	// A CRL that never validated only has counters
+	ObserveFetch("NHN Unreachable CA", OutcomeError, time.Second)
+
+	RetainCRLs([]string{"NHN Internal CA"})
+
+	series := crlSeries(t)
+	if series["NHN Removed CA"] != 0 || series["NHN Unreachable CA"] != 0 {
+		t.Errorf("removed CRLs still have series: %v", series)
+	}
+	if series["NHN Internal CA"] == 0 {
+		t.Errorf("retained CRL has no series: %v", series)
+	}
+}
@@ -0,0 +1,266 @@
+package revocation
+
+import (
+	"bytes"
+	"crypto/x509"
+	"math/big"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"trawler/pkg/crl"
+)
+
+// Revocation statuses returned by Lookup
+const (
+	StatusGood    = "good"
+	StatusRevoked = "revoked"
+	StatusUnknown = "unknown"
+)
+
+// Issuer identifies the CA a certificate was issued by. Fields left empty are not compared.
+type Issuer struct {
+	DN        string // Issuer DN as formatted by pkix.Name.String, compared case-insensitively
+	RawIssuer []byte // DER encoded issuer DN, as in the certificate
+	KeyID     []byte // Authority key identifier
+}
+
+// Result is the revocation status of a certificate
+type Result struct {
+	Status         string     `json:"status"`
+	Serial         string     `json:"serial"`
+	Issuer         string     `json:"issuer,omitempty"`
+	CRL            string     `json:"crl,omitempty"` // Name of the newest CRL the status was derived from
+	RevocationTime *time.Time `json:"revocationTime,omitempty"`
+	ReasonCode     *int       `json:"reasonCode,omitempty"`
+	Reason         string     `json:"reason,omitempty"`
+	ThisUpdate     *time.Time `json:"thisUpdate,omitempty"`
+	NextUpdate     *time.Time `json:"nextUpdate,omitempty"`
+	Detail         string     `json:"detail,omitempty"` // Why the status is unknown
+}
+
+// indexedCRL holds the revoked serials of a single CRL
+type indexedCRL struct {
+	name       string
+	issuer     string
+	rawIssuer  []byte
+	keyID      []byte
+	delta      bool
+	thisUpdate time.Time
+	nextUpdate time.Time
+	entries    map[string]x509.RevocationListEntry // Entries by serial number
+}
+
+// matches reports whether the CRL was issued by the given issuer.
+// When both key identifiers are known they decide, so that a renewed CA key with the same DN is told apart.
+func (c *indexedCRL) matches(issuer Issuer) bool {
+	if len(issuer.KeyID) > 0 && len(c.keyID) > 0 {
+		return bytes.Equal(issuer.KeyID, c.keyID)
+	}
+	if len(issuer.RawIssuer) > 0 {
+		return bytes.Equal(issuer.RawIssuer, c.rawIssuer)
+	}
+	return issuer.DN != "" && strings.EqualFold(issuer.DN, c.issuer)
+}
+
+// Index holds the revoked serial numbers of all valid CRLs, by issuer
+type Index struct {
+	mu   sync.RWMutex
+	crls map[string]*indexedCRL // Indexed CRLs by name
+}
+
+// NewIndex creates an empty revocation index
+func NewIndex() *Index {
+	return &Index{crls: make(map[string]*indexedCRL)}
+}
+
+// Update replaces the entries of the named CRL with those of a validated CRL
+func (i *Index) Update(name string, revocationList *x509.RevocationList) error {
+	_, isDelta, err := crl.DeltaCRLBaseNumber(revocationList)
+	if err != nil {
+		return err
+	}
+
+	indexed := &indexedCRL{
+		name:       name,
+		issuer:     revocationList.Issuer.String(),
+		rawIssuer:  revocationList.RawIssuer,
+		keyID:      revocationList.AuthorityKeyId,
+		delta:      isDelta,
+		thisUpdate: revocationList.ThisUpdate,
+		nextUpdate: revocationList.NextUpdate,
+		entries:    make(map[string]x509.RevocationListEntry, len(revocationList.RevokedCertificateEntries)),
+	}
+	for _, entry := range revocationList.RevokedCertificateEntries {
+		indexed.entries[serialKey(entry.SerialNumber)] = entry
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.crls[name] = indexed
+	return nil
+}
+
+// Remove drops the named CRL from the index
+func (i *Index) Remove(name string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	delete(i.crls, name)
+}
+
+// Names returns the names of the indexed CRLs
+func (i *Index) Names() []string {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	names := make([]string, 0, len(i.crls))
+	for name := range i.crls {
+		names = append(names, name)
+	}
+	return names
+}
+
+// Lookup returns the revocation status of a serial number.
+// The newest unexpired base CRL of the issuer is used, combined with the newest delta CRL issued after it.
+// An issuer given without key identifier matches the CRLs of every key of the CA, such as the old and the new key
+// after a rollover. Serial numbers are unique per issuer name, so the serial is checked against the CRLs of each key.
+// The status is unknown when no unexpired CRL of the issuer is indexed.
+func (i *Index) Lookup(issuer Issuer, serial *big.Int, now time.Time) Result {
+	result := Result{Status: StatusUnknown, Serial: serialKey(serial)}
+
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	current, expired := i.current(issuer, now)
+	if len(current) == 0 {
+		result.Detail = "no CRL of the issuer is known"
+		if expired {
+			result.Detail = "the CRL of the issuer has expired"
+		}
+		return result
+	}
+
+	for _, crls := range current {
+		keyResult := crls.lookup(result.Serial)
+		if keyResult.Status == StatusRevoked {
+			return keyResult
+		}
+		// Without a revocation, the status is reported from the newest CRL of the issuer
+		if result.Status == StatusUnknown || keyResult.ThisUpdate.After(*result.ThisUpdate) {
+			result = keyResult
+		}
+	}
+	return result
+}
+
+// RevokedSerials returns the serial numbers listed in the current base and delta CRLs of the issuer
+func (i *Index) RevokedSerials(issuer Issuer, now time.Time) []*big.Int {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	current, _ := i.current(issuer, now)
+	var serials []*big.Int
+	for _, crls := range current {
+		for _, indexed := range []*indexedCRL{crls.base, crls.delta} {
+			if indexed == nil {
+				continue
+			}
+			for _, entry := range indexed.entries {
+				serials = append(serials, entry.SerialNumber)
+			}
+		}
+	}
+	return serials
+}
+
+// currentCRLs is the newest unexpired base CRL signed by a key, and the newest delta CRL issued after it
+type currentCRLs struct {
+	base  *indexedCRL
+	delta *indexedCRL
+}
+
+// lookup returns the revocation status of a serial number according to the base and delta CRL
+func (c currentCRLs) lookup(serial string) Result {
+	result := Result{Status: StatusGood, Serial: serial}
+	result.setCRL(c.base)
+	if entry, revoked := c.base.entries[serial]; revoked {
+		result.setRevoked(entry)
+	}
+
+	// The delta is newer, so the status is as current as the delta, even when the serial is not listed in it
+	if c.delta != nil {
+		result.setCRL(c.delta)
+		if entry, listed := c.delta.entries[serial]; listed {
+			if entry.ReasonCode == crl.ReasonRemoveFromCRL {
+				result.Status = StatusGood
+				result.RevocationTime, result.ReasonCode, result.Reason = nil, nil, ""
+			} else {
+				result.setRevoked(entry)
+			}
+		}
+	}
+	return result
+}
+
+// current returns the current CRLs of each key of the issuer that has an unexpired base CRL.
+// expired is true if CRLs of the issuer were skipped because they have expired. Must be called with the lock held.
+func (i *Index) current(issuer Issuer, now time.Time) (current []currentCRLs, expired bool) {
+	byKey := make(map[string]*currentCRLs)
+	var keys []string
+	for _, indexed := range i.crls {
+		if !indexed.matches(issuer) {
+			continue
+		}
+		if now.After(indexed.nextUpdate) {
+			expired = true
+			continue
+		}
+		key := string(indexed.keyID)
+		crls, exists := byKey[key]
+		if !exists {
+			crls = &currentCRLs{}
+			byKey[key] = crls
+			keys = append(keys, key)
+		}
+		if indexed.delta {
+			if crls.delta == nil || indexed.thisUpdate.After(crls.delta.thisUpdate) {
+				crls.delta = indexed
+			}
+		} else if crls.base == nil || indexed.thisUpdate.After(crls.base.thisUpdate) {
+			crls.base = indexed
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		crls := byKey[key]
+		if crls.base == nil {
+			continue
+		}
+		// A delta CRL older than the base has been superseded by it
+		if crls.delta != nil && crls.delta.thisUpdate.Before(crls.base.thisUpdate) {
+			crls.delta = nil
+		}
+		current = append(current, *crls)
+	}
+	return current, expired
+}
+
+func (r *Result) setCRL(indexed *indexedCRL) {
+	thisUpdate, nextUpdate := indexed.thisUpdate, indexed.nextUpdate
+	r.CRL = indexed.name
+	r.Issuer = indexed.issuer
+	r.ThisUpdate = &thisUpdate
+	r.NextUpdate = &nextUpdate
+}
+
+func (r *Result) setRevoked(entry x509.RevocationListEntry) {
+	revocationTime, reasonCode := entry.RevocationTime, entry.ReasonCode
+	r.Status = StatusRevoked
+	r.RevocationTime = &revocationTime
+	r.ReasonCode = &reasonCode
+	r.Reason = crl.ReasonName(reasonCode)
+}
+
+// serialKey formats a serial number as lower case hex, as used by the index and in results
+func serialKey(serial *big.Int) string {
+	return serial.Text(16)
+}
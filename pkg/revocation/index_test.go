@@ -0,0 +1,102 @@
+package revocation
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"testing"
+	"time"
+)
+
+// newTestCRL returns a CRL signed by a new key of the CA with the given name, revoking the given serials
+func newTestCRL(t *testing.T, caName pkix.Name, keyID []byte, thisUpdate time.Time, revoked ...int64) *x509.RevocationList {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               caName,
+		SubjectKeyId:          keyID,
+		NotBefore:             thisUpdate.Add(-24 * time.Hour),
+		NotAfter:              thisUpdate.Add(365 * 24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caCertificate, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	crlTemplate := &x509.RevocationList{
+		Number:     big.NewInt(thisUpdate.Unix()),
+		ThisUpdate: thisUpdate,
+		NextUpdate: thisUpdate.Add(7 * 24 * time.Hour),
+	}
+	for _, serial := range revoked {
+		crlTemplate.RevokedCertificateEntries = append(crlTemplate.RevokedCertificateEntries, x509.RevocationListEntry{
+			SerialNumber:   big.NewInt(serial),
+			RevocationTime: thisUpdate.Add(-time.Hour),
+		})
+	}
+	crlDER, err := x509.CreateRevocationList(rand.Reader, crlTemplate, caCertificate, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	revocationList, err := x509.ParseRevocationList(crlDER)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return revocationList
+}
+
+func TestLookupAfterKeyRollover(t *testing.T) {
+	caName := pkix.Name{CommonName: "NHN Internal CA", Organization: []string{"Norsk helsenett SF"}}
+	oldKeyID, newKeyID := []byte{0x01, 0x01}, []byte{0x02, 0x02}
+	now := time.Now()
+
+	// The old key keeps publishing a CRL for the certificates it issued, next to the newer CRL of the new key
+	index := NewIndex()
+	if err := index.Update("NHN Internal CA", newTestCRL(t, caName, oldKeyID, now.Add(-2*time.Hour), 0x10)); err != nil {
+		t.Fatal(err)
+	}
+	if err := index.Update("NHN Internal CA(1)", newTestCRL(t, caName, newKeyID, now.Add(-time.Hour), 0x20)); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		issuer Issuer
+		serial int64
+		status string
+		crl    string
+	}{
+		{"DN only, revoked by old key", Issuer{DN: caName.String()}, 0x10, StatusRevoked, "NHN Internal CA"},
+		{"DN only, revoked by new key", Issuer{DN: caName.String()}, 0x20, StatusRevoked, "NHN Internal CA(1)"},
+		{"DN only, not revoked", Issuer{DN: caName.String()}, 0x30, StatusGood, "NHN Internal CA(1)"},
+		{"old key", Issuer{KeyID: oldKeyID}, 0x10, StatusRevoked, "NHN Internal CA"},
+		{"new key", Issuer{KeyID: newKeyID}, 0x10, StatusGood, "NHN Internal CA(1)"},
+		{"unknown key", Issuer{KeyID: []byte{0x03}}, 0x10, StatusUnknown, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := index.Lookup(tt.issuer, big.NewInt(tt.serial), now)
+			if result.Status != tt.status || result.CRL != tt.crl {
+				t.Errorf("Lookup = %s from %q, want %s from %q", result.Status, result.CRL, tt.status, tt.crl)
+			}
+		})
+	}
+
+	if serials := index.RevokedSerials(Issuer{DN: caName.String()}, now); len(serials) != 2 {
+		t.Errorf("RevokedSerials = %v, want the serials revoked by both keys", serials)
+	}
+}
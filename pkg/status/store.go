@@ -0,0 +1,289 @@
+package status
+
+import (
+	"crypto/x509"
+	"sort"
+	"sync"
+	"time"
+	"trawler/pkg/crl"
+)
+
+// Kinds of CRLs in the status store
+const (
+	KindOnline  = "online"
+	KindDelta   = "delta"
+	KindOffline = "offline"
+)
+
+// Definition describes a CRL as configured
+type Definition struct {
+	Name   string
+	Kind   string
+	Source string // URL of an online CRL, or file path of an offline CRL
+}
+
+// BackendStatus is the result of the last publication of a CRL to a storage backend
+type BackendStatus struct {
+	Outcome     string     `json:"outcome"`
+	Hash        string     `json:"hash,omitempty"`
+	LastAttempt time.Time  `json:"lastAttempt"`
+	LastSuccess *time.Time `json:"lastSuccess,omitempty"`
+	Error       string     `json:"error,omitempty"`
+}
+
+// QuarantineStatus describes a version of a CRL that is held back because of anomalies
+type QuarantineStatus struct {
+	Hash      string    `json:"hash"`
+	CRLNumber string    `json:"crlNumber,omitempty"`
+	Since     time.Time `json:"since"`
+	Anomalies []string  `json:"anomalies"`
+	Released  bool      `json:"released"` // Released through the admin API, published when it is retrieved again
+}
+
+// CRLStatus is what Trawler currently knows about a CRL.
+// Outcomes use the same values as the outcome label of the metrics.
+type CRLStatus struct {
+	Name            string                   `json:"name"`
+	Kind            string                   `json:"kind"`
+	Source          string                   `json:"source"`
+	LastFetch       *time.Time               `json:"lastFetch,omitempty"`
+	FetchOutcome    string                   `json:"fetchOutcome,omitempty"`
+	FetchError      string                   `json:"fetchError,omitempty"`
+	LastValidation  *time.Time               `json:"lastValidation,omitempty"`
+	Validation      string                   `json:"validation,omitempty"`
+	ValidationError string                   `json:"validationError,omitempty"`
+	LastValid       *time.Time               `json:"lastValid,omitempty"` // When the CRL below was last found valid
+	Hash            string                   `json:"hash,omitempty"`
+	CRLNumber       string                   `json:"crlNumber,omitempty"`
+	ThisUpdate      *time.Time               `json:"thisUpdate,omitempty"`
+	NextUpdate      *time.Time               `json:"nextUpdate,omitempty"`
+	NextCRLPublish  *time.Time               `json:"nextCRLPublish,omitempty"`
+	RevokedEntries  int                      `json:"revokedEntries"`
+	NextFetch       *time.Time               `json:"nextFetch,omitempty"`
+	LastChange      *time.Time               `json:"lastChange,omitempty"` // When a changed CRL was last compared with the stored version
+	LastDiff        string                   `json:"lastDiff,omitempty"`   // Summary of that comparison
+	Quarantine      *QuarantineStatus        `json:"quarantine,omitempty"`
+	Backends        map[string]BackendStatus `json:"backends"`
+	diff            *crl.Diff
+}
+
+// Store keeps the status of every configured CRL for the admin API
+type Store struct {
+	mu   sync.RWMutex
+	crls map[string]*CRLStatus
+}
+
+// NewStore creates an empty status store
+func NewStore() *Store {
+	return &Store{crls: make(map[string]*CRLStatus)}
+}
+
+// Sync sets the CRLs in the store to the configured ones, keeping the status of CRLs that are still configured
+func (s *Store) Sync(definitions []Definition) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	configured := make(map[string]bool, len(definitions))
+	for _, definition := range definitions {
+		configured[definition.Name] = true
+		crlStatus := s.entry(definition.Name)
+		crlStatus.Kind = definition.Kind
+		crlStatus.Source = definition.Source
+	}
+	for name := range s.crls {
+		if !configured[name] {
+			delete(s.crls, name)
+		}
+	}
+}
+
+// RecordFetch stores the result of retrieving a CRL from its source
+func (s *Store) RecordFetch(name string, outcome string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	crlStatus := s.entry(name)
+	now := time.Now()
+	crlStatus.LastFetch = &now
+	crlStatus.FetchOutcome = outcome
+	crlStatus.FetchError = errorString(err)
+}
+
+// RecordValidation stores the result of validating a retrieved CRL
+func (s *Store) RecordValidation(name string, outcome string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	crlStatus := s.entry(name)
+	now := time.Now()
+	crlStatus.LastValidation = &now
+	crlStatus.Validation = outcome
+	crlStatus.ValidationError = errorString(err)
+}
+
+// RecordValid stores the details of a CRL that passed validation
+func (s *Store) RecordValid(name string, hash string, decodedCRL *x509.RevocationList, nextCRLPublish time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	crlStatus := s.entry(name)
+	now := time.Now()
+	crlStatus.LastValid = &now
+	crlStatus.Hash = hash
+	crlStatus.CRLNumber = ""
+	if decodedCRL.Number != nil {
+		crlStatus.CRLNumber = decodedCRL.Number.String()
+	}
+	crlStatus.ThisUpdate = timePtr(decodedCRL.ThisUpdate)
+	crlStatus.NextUpdate = timePtr(decodedCRL.NextUpdate)
+	crlStatus.NextCRLPublish = timePtr(nextCRLPublish)
+	crlStatus.RevokedEntries = len(decodedCRL.RevokedCertificateEntries)
+}
+
+// RecordPublish stores the result of publishing a CRL to a storage backend
+func (s *Store) RecordPublish(name string, backend string, outcome string, hash string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	crlStatus := s.entry(name)
+	backendStatus := crlStatus.Backends[backend]
+	backendStatus.Outcome = outcome
+	backendStatus.Hash = hash
+	backendStatus.LastAttempt = time.Now()
+	backendStatus.Error = errorString(err)
+	if err == nil {
+		backendStatus.LastSuccess = timePtr(backendStatus.LastAttempt)
+	}
+	crlStatus.Backends[backend] = backendStatus
+}
+
+// RecordNextFetch stores when a CRL is scheduled to be fetched again
+func (s *Store) RecordNextFetch(name string, nextFetch time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entry(name).NextFetch = timePtr(nextFetch)
+}
+
+// RecordDiff stores the changes of a CRL compared with the version stored before
+func (s *Store) RecordDiff(name string, diff *crl.Diff) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	crlStatus := s.entry(name)
+	now := time.Now()
+	crlStatus.LastChange = &now
+	crlStatus.LastDiff = diff.String()
+	crlStatus.diff = diff
+}
+
+// Diff returns the last recorded changes of a CRL and when they were recorded.
+// Diffs are not modified after they are recorded, so the diff is shared rather than copied.
+func (s *Store) Diff(name string) (*crl.Diff, time.Time, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	crlStatus, exists := s.crls[name]
+	if !exists || crlStatus.diff == nil {
+		return nil, time.Time{}, false
+	}
+	return crlStatus.diff, *crlStatus.LastChange, true
+}
+
+// RecordQuarantine stores that a version of a CRL is held back.
+// A release of the same version is kept, so that it can still be published.
+func (s *Store) RecordQuarantine(name string, hash string, crlNumber string, anomalies []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	crlStatus := s.entry(name)
+	if crlStatus.Quarantine != nil && crlStatus.Quarantine.Hash == hash {
+		crlStatus.Quarantine.Anomalies = anomalies
+		return
+	}
+	crlStatus.Quarantine = &QuarantineStatus{Hash: hash, CRLNumber: crlNumber, Since: time.Now(), Anomalies: anomalies}
+}
+
+// ReleaseQuarantine allows the quarantined version of a CRL to be published.
+// It returns false if the CRL is not quarantined.
+func (s *Store) ReleaseQuarantine(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	crlStatus, exists := s.crls[name]
+	if !exists || crlStatus.Quarantine == nil {
+		return false
+	}
+	crlStatus.Quarantine.Released = true
+	return true
+}
+
+// QuarantineReleased reports whether the given version of a CRL was released from quarantine
+func (s *Store) QuarantineReleased(name string, hash string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	crlStatus, exists := s.crls[name]
+	return exists && crlStatus.Quarantine != nil && crlStatus.Quarantine.Hash == hash && crlStatus.Quarantine.Released
+}
+
+// ClearQuarantine removes the quarantine of a CRL, once a version without anomalies or a released version is accepted
+func (s *Store) ClearQuarantine(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if crlStatus, exists := s.crls[name]; exists {
+		crlStatus.Quarantine = nil
+	}
+}
+
+// Get returns a copy of the status of a CRL
+func (s *Store) Get(name string) (CRLStatus, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	crlStatus, exists := s.crls[name]
+	if !exists {
+		return CRLStatus{}, false
+	}
+	return crlStatus.copy(), true
+}
+
+// List returns a copy of the status of all CRLs, sorted by name
+func (s *Store) List() []CRLStatus {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	crls := make([]CRLStatus, 0, len(s.crls))
+	for _, crlStatus := range s.crls {
+		crls = append(crls, crlStatus.copy())
+	}
+	sort.Slice(crls, func(i, j int) bool { return crls[i].Name < crls[j].Name })
+	return crls
+}
+
+// entry returns the status of a CRL, creating it if needed. Must be called with the lock held.
+func (s *Store) entry(name string) *CRLStatus {
+	crlStatus, exists := s.crls[name]
+	if !exists {
+		crlStatus = &CRLStatus{Name: name, Backends: make(map[string]BackendStatus)}
+		s.crls[name] = crlStatus
+	}
+	return crlStatus
+}
+
+func (c *CRLStatus) copy() CRLStatus {
+	crlStatus := *c
+	crlStatus.Backends = make(map[string]BackendStatus, len(c.Backends))
+	for name, backendStatus := range c.Backends {
+		crlStatus.Backends[name] = backendStatus
+	}
+	if c.Quarantine != nil {
+		quarantine := *c.Quarantine
+		quarantine.Anomalies = append([]string(nil), c.Quarantine.Anomalies...)
+		crlStatus.Quarantine = &quarantine
+	}
+	return crlStatus
+}
+
+// timePtr returns nil for the zero time, so that unknown times are left out of responses
+func timePtr(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+func errorString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
@@ -0,0 +1,75 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+)
+
+// ErrNotFound is returned by a Backend when the requested object does not exist
+var ErrNotFound = errors.New("object not found")
+
+// ObjectInfo describes an object stored in a Backend
+type ObjectInfo struct {
+	Key          string
+	Size         int64
+	LastModified time.Time
+}
+
+// Backend is a storage location that CRLs can be published to
+type Backend interface {
+	Name() string
+	Get(ctx context.Context, key string) ([]byte, error)
+	Put(ctx context.Context, key string, data []byte) error
+	Stat(ctx context.Context, key string) (*ObjectInfo, error)
+	List(ctx context.Context, prefix string) ([]ObjectInfo, error)
+	Delete(ctx context.Context, key string) error
+	HealthCheck(ctx context.Context) error
+}
+
+// prefixedBackend places all keys of the wrapped backend below a common prefix
+type prefixedBackend struct {
+	Backend
+	prefix string
+}
+
+// WithPrefix returns a Backend that stores all objects below the given prefix of the wrapped backend
+func WithPrefix(backend Backend, prefix string) Backend {
+	if prefix == "" {
+		return backend
+	}
+	return &prefixedBackend{Backend: backend, prefix: prefix}
+}
+
+func (b *prefixedBackend) Get(ctx context.Context, key string) ([]byte, error) {
+	return b.Backend.Get(ctx, b.prefix+key)
+}
+
+func (b *prefixedBackend) Put(ctx context.Context, key string, data []byte) error {
+	return b.Backend.Put(ctx, b.prefix+key, data)
+}
+
+func (b *prefixedBackend) Stat(ctx context.Context, key string) (*ObjectInfo, error) {
+	info, err := b.Backend.Stat(ctx, b.prefix+key)
+	if err != nil {
+		return nil, err
+	}
+	info.Key = strings.TrimPrefix(info.Key, b.prefix)
+	return info, nil
+}
+
+func (b *prefixedBackend) List(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	objects, err := b.Backend.List(ctx, b.prefix+prefix)
+	if err != nil {
+		return nil, err
+	}
+	for i := range objects {
+		objects[i].Key = strings.TrimPrefix(objects[i].Key, b.prefix)
+	}
+	return objects, nil
+}
+
+func (b *prefixedBackend) Delete(ctx context.Context, key string) error {
+	return b.Backend.Delete(ctx, b.prefix+key)
+}
@@ -0,0 +1,95 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"trawler/pkg/crl"
+)
+
+// Formats CRLs can be stored in
+const (
+	FormatDER  = "der"
+	FormatPEM  = "pem"
+	FormatBoth = "both" // DER under the .crl key, and PEM next to it under a .pem key
+)
+
+const (
+	crlSuffix = ".crl"
+	pemSuffix = ".pem"
+)
+
+// formatBackend stores the CRLs of the wrapped backend in PEM form, alone or next to the DER form.
+// CRLs are always handed to and returned from it in DER form, so that hashes compare equal regardless of the format.
+type formatBackend struct {
+	Backend
+	format string
+}
+
+// WithFormat returns a Backend that stores objects with a .crl key in the given format.
+// The DER format stores CRLs as they are, and returns the wrapped backend itself.
+func WithFormat(backend Backend, format string) (Backend, error) {
+	switch strings.ToLower(format) {
+	case "", FormatDER:
+		return backend, nil
+	case FormatPEM:
+		return &formatBackend{Backend: backend, format: FormatPEM}, nil
+	case FormatBoth:
+		return &formatBackend{Backend: backend, format: FormatBoth}, nil
+	default:
+		return nil, fmt.Errorf("unsupported format %q, must be der, pem or both", format)
+	}
+}
+
+func (b *formatBackend) Get(ctx context.Context, key string) ([]byte, error) {
+	data, err := b.Backend.Get(ctx, key)
+	if err != nil || !strings.HasSuffix(key, crlSuffix) {
+		return data, err
+	}
+	return crl.DecodePEM(data)
+}
+
+func (b *formatBackend) Put(ctx context.Context, key string, data []byte) error {
+	if !strings.HasSuffix(key, crlSuffix) {
+		return b.Backend.Put(ctx, key, data)
+	}
+	if b.format == FormatPEM {
+		return b.Backend.Put(ctx, key, crl.EncodePEM(data))
+	}
+	// Write the PEM form first, so that the DER form, which is compared against, is only updated once both are stored
+	if err := b.Backend.Put(ctx, pemKey(key), crl.EncodePEM(data)); err != nil {
+		return err
+	}
+	return b.Backend.Put(ctx, key, data)
+}
+
+// List leaves out the PEM copies, so that each CRL is listed once under its .crl key
+func (b *formatBackend) List(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	objects, err := b.Backend.List(ctx, prefix)
+	if err != nil || b.format != FormatBoth {
+		return objects, err
+	}
+	listed := objects[:0]
+	for _, object := range objects {
+		if !strings.HasSuffix(object.Key, pemSuffix) {
+			listed = append(listed, object)
+		}
+	}
+	return listed, nil
+}
+
+func (b *formatBackend) Delete(ctx context.Context, key string) error {
+	if b.format != FormatBoth || !strings.HasSuffix(key, crlSuffix) {
+		return b.Backend.Delete(ctx, key)
+	}
+	if err := b.Backend.Delete(ctx, pemKey(key)); err != nil && !errors.Is(err, ErrNotFound) {
+		return err
+	}
+	return b.Backend.Delete(ctx, key)
+}
+
+// pemKey returns the key of the PEM copy of a CRL stored under a .crl key
+func pemKey(key string) string {
+	return strings.TrimSuffix(key, crlSuffix) + pemSuffix
+}
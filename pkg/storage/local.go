@@ -1,8 +1,12 @@
 package storage
 
 import (
+	"context"
 	"fmt"
+	"io/fs"
 	"os"
+	"path/filepath"
+	"strings"
 )
 
 func ValidateLocalStoragePaths(paths ...string) error {
@@ -90,3 +94,102 @@ func CopyFolder(srcPath, dstPath string) error {
 	}
 	return nil
 }
+
+// LocalBackend stores objects as files below a base directory
+type LocalBackend struct {
+	name     string
+	basePath string
+}
+
+// NewLocalBackend creates a Backend storing objects below basePath
+func NewLocalBackend(name string, basePath string) *LocalBackend {
+	return &LocalBackend{name: name, basePath: basePath}
+}
+
+func (b *LocalBackend) Name() string {
+	return b.name
+}
+
+func (b *LocalBackend) path(key string) string {
+	return filepath.Join(b.basePath, filepath.FromSlash(key))
+}
+
+func (b *LocalBackend) Get(ctx context.Context, key string) ([]byte, error) {
+	data, err := os.ReadFile(b.path(key))
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func (b *LocalBackend) Put(ctx context.Context, key string, data []byte) error {
+	filePath := b.path(key)
+	err := CreateFolderIfNotExists(filepath.Dir(filePath))
+	if err != nil {
+		return err
+	}
+	return SaveCRLToFile(filePath, data)
+}
+
+func (b *LocalBackend) Stat(ctx context.Context, key string) (*ObjectInfo, error) {
+	info, err := os.Stat(b.path(key))
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	return &ObjectInfo{Key: key, Size: info.Size(), LastModified: info.ModTime()}, nil
+}
+
+func (b *LocalBackend) List(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	var objects []ObjectInfo
+	err := filepath.WalkDir(b.basePath, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			return nil
+		}
+		relPath, err := filepath.Rel(b.basePath, filePath)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(relPath)
+		if !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		objects = append(objects, ObjectInfo{Key: key, Size: info.Size(), LastModified: info.ModTime()})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return objects, nil
+}
+
+func (b *LocalBackend) Delete(ctx context.Context, key string) error {
+	err := os.Remove(b.path(key))
+	if os.IsNotExist(err) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// HealthCheck verifies that the base directory exists and is writable
+func (b *LocalBackend) HealthCheck(ctx context.Context) error {
+	if !CheckIfFolderExists(b.basePath) {
+		return fmt.Errorf("storage path does not exist: %s", b.basePath)
+	}
+	probe, err := os.CreateTemp(b.basePath, ".healthcheck-*")
+	if err != nil {
+		return fmt.Errorf("storage path is not writable: %v", err)
+	}
+	probe.Close()
+	return os.Remove(probe.Name())
+}
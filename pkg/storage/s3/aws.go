@@ -6,10 +6,13 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"strings"
 	"trawler/pkg/logging"
+	"trawler/pkg/storage"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	awsConfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
 	awsS3 "github.com/aws/aws-sdk-go-v2/service/s3"
 )
 
@@ -145,3 +148,129 @@ func AWSHeadBucketInput(bucketName string) *awsS3.HeadBucketInput {
 		Bucket: aws.String(bucketName),
 	}
 }
+
+// AWSCreateS3ClientFromConfig creates an S3 client from an explicit configuration instead of the environment.
+// Credentials fall back to the default AWS credential chain when no API key is set.
+func AWSCreateS3ClientFromConfig(config *S3Config) (*Client, error) {
+	var loadOptions []func(*awsConfig.LoadOptions) error
+	if config.Region != "" {
+		loadOptions = append(loadOptions, awsConfig.WithRegion(config.Region))
+	}
+	if config.APIKey != "" {
+		loadOptions = append(loadOptions, awsConfig.WithCredentialsProvider(
+			credentials.NewStaticCredentialsProvider(config.APIKey, string(config.APISecret), ""),
+		))
+	}
+
+	sdkConfig, err := awsConfig.LoadDefaultConfig(context.TODO(), loadOptions...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load AWS SDK config, %v", err)
+	}
+
+	client := awsS3.NewFromConfig(sdkConfig, func(o *awsS3.Options) {
+		if config.ServiceEndpoint != "" {
+			o.BaseEndpoint = aws.String(config.ServiceEndpoint)
+			o.UsePathStyle = true
+		}
+		o.EndpointOptions.DisableHTTPS = !config.SSLEnabled
+	})
+	return client, nil
+}
+
+// AWSBackend publishes objects to a bucket using the AWS SDK
+type AWSBackend struct {
+	name   string
+	client *Client
+	bucket string
+}
+
+// NewAWSBackend creates a storage backend for the given bucket using an existing AWS S3 client
+func NewAWSBackend(name string, client *Client, bucket string) *AWSBackend {
+	return &AWSBackend{name: name, client: client, bucket: bucket}
+}
+
+func (b *AWSBackend) Name() string {
+	return b.name
+}
+
+func (b *AWSBackend) Get(ctx context.Context, key string) ([]byte, error) {
+	output, err := b.client.GetObject(ctx, AWSGetObjectInput(b.bucket, key))
+	if err != nil {
+		if awsIsNotFound(err) {
+			return nil, storage.ErrNotFound
+		}
+		return nil, err
+	}
+	defer output.Body.Close()
+	return io.ReadAll(output.Body)
+}
+
+func (b *AWSBackend) Put(ctx context.Context, key string, data []byte) error {
+	input := AWSPutObjectInput(b.bucket, key, data)
+	input.ContentType = aws.String(objectContentType(key, data))
+	_, err := b.client.PutObject(ctx, input)
+	if err != nil {
+		return fmt.Errorf("Failed to upload object to bucket %s with key %s: %v", b.bucket, key, err)
+	}
+	return nil
+}
+
+func (b *AWSBackend) Stat(ctx context.Context, key string) (*storage.ObjectInfo, error) {
+	output, err := b.client.HeadObject(ctx, &awsS3.HeadObjectInput{
+		Bucket: aws.String(b.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		if awsIsNotFound(err) {
+			return nil, storage.ErrNotFound
+		}
+		return nil, err
+	}
+	return &storage.ObjectInfo{
+		Key:          key,
+		Size:         aws.ToInt64(output.ContentLength),
+		LastModified: aws.ToTime(output.LastModified),
+	}, nil
+}
+
+func (b *AWSBackend) List(ctx context.Context, prefix string) ([]storage.ObjectInfo, error) {
+	var objects []storage.ObjectInfo
+	paginator := awsS3.NewListObjectsV2Paginator(b.client, &awsS3.ListObjectsV2Input{
+		Bucket: aws.String(b.bucket),
+		Prefix: aws.String(prefix),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, object := range page.Contents {
+			objects = append(objects, storage.ObjectInfo{
+				Key:          aws.ToString(object.Key),
+				Size:         aws.ToInt64(object.Size),
+				LastModified: aws.ToTime(object.LastModified),
+			})
+		}
+	}
+	return objects, nil
+}
+
+func (b *AWSBackend) Delete(ctx context.Context, key string) error {
+	_, err := b.client.DeleteObject(ctx, &awsS3.DeleteObjectInput{
+		Bucket: aws.String(b.bucket),
+		Key:    aws.String(key),
+	})
+	return err
+}
+
+func (b *AWSBackend) HealthCheck(ctx context.Context) error {
+	_, err := b.client.HeadBucket(ctx, AWSHeadBucketInput(b.bucket))
+	if err != nil {
+		return fmt.Errorf("Failed to access bucket %s: %v", b.bucket, err)
+	}
+	return nil
+}
+
+func awsIsNotFound(err error) bool {
+	return strings.Contains(err.Error(), "NoSuchKey") || strings.Contains(err.Error(), "NotFound")
+}
@@ -2,11 +2,15 @@ package s3
 
 import (
 	"bytes"
+	"context"
 	"fmt"
+	"io"
 	"os"
 	"trawler/pkg/logging"
+	"trawler/pkg/storage"
 
 	"github.com/IBM/ibm-cos-sdk-go/aws"
+	"github.com/IBM/ibm-cos-sdk-go/aws/awserr"
 	"github.com/IBM/ibm-cos-sdk-go/aws/credentials/ibmiam"
 	"github.com/IBM/ibm-cos-sdk-go/aws/session"
 
@@ -119,3 +123,108 @@ func IBMCreateS3Bucket(client *s3.S3, bucketName string) error {
 
 	return nil
 }
+
+// IBMBackend publishes objects to a bucket on IBM Cloud Object Storage
+type IBMBackend struct {
+	name   string
+	client *s3.S3
+	bucket string
+}
+
+// NewIBMBackend connects to IBM COS and creates a storage backend for the bucket set in the config
+func NewIBMBackend(name string, config *S3Config) *IBMBackend {
+	return &IBMBackend{name: name, client: IBMConnectToS3(config), bucket: config.Bucket}
+}
+
+func (b *IBMBackend) Name() string {
+	return b.name
+}
+
+func (b *IBMBackend) Get(ctx context.Context, key string) ([]byte, error) {
+	output, err := b.client.GetObjectWithContext(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(b.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, ibmTranslateError(err)
+	}
+	defer output.Body.Close()
+	return io.ReadAll(output.Body)
+}
+
+func (b *IBMBackend) Put(ctx context.Context, key string, data []byte) error {
+	_, err := b.client.PutObjectWithContext(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(b.bucket),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(data),
+		ContentType: aws.String(objectContentType(key, data)),
+	})
+	if err != nil {
+		return fmt.Errorf("Failed to upload object to bucket %s with key %s: %v", b.bucket, key, err)
+	}
+	return nil
+}
+
+func (b *IBMBackend) Stat(ctx context.Context, key string) (*storage.ObjectInfo, error) {
+	output, err := b.client.HeadObjectWithContext(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(b.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, ibmTranslateError(err)
+	}
+	return &storage.ObjectInfo{
+		Key:          key,
+		Size:         aws.Int64Value(output.ContentLength),
+		LastModified: aws.TimeValue(output.LastModified),
+	}, nil
+}
+
+func (b *IBMBackend) List(ctx context.Context, prefix string) ([]storage.ObjectInfo, error) {
+	var objects []storage.ObjectInfo
+	input := &s3.ListObjectsV2Input{
+		Bucket: aws.String(b.bucket),
+		Prefix: aws.String(prefix),
+	}
+	err := b.client.ListObjectsV2PagesWithContext(ctx, input, func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+		for _, object := range page.Contents {
+			objects = append(objects, storage.ObjectInfo{
+				Key:          aws.StringValue(object.Key),
+				Size:         aws.Int64Value(object.Size),
+				LastModified: aws.TimeValue(object.LastModified),
+			})
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return objects, nil
+}
+
+func (b *IBMBackend) Delete(ctx context.Context, key string) error {
+	_, err := b.client.DeleteObjectWithContext(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(b.bucket),
+		Key:    aws.String(key),
+	})
+	return err
+}
+
+func (b *IBMBackend) HealthCheck(ctx context.Context) error {
+	_, err := b.client.HeadBucketWithContext(ctx, &s3.HeadBucketInput{
+		Bucket: aws.String(b.bucket),
+	})
+	if err != nil {
+		return fmt.Errorf("Failed to check status of bucket %s: %v", b.bucket, err)
+	}
+	return nil
+}
+
+func ibmTranslateError(err error) error {
+	if awsErr, ok := err.(awserr.Error); ok {
+		if awsErr.Code() == s3.ErrCodeNoSuchKey || awsErr.Code() == "NotFound" {
+			return storage.ErrNotFound
+		}
+	}
+	return err
+}
@@ -4,8 +4,10 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"trawler/pkg/logging"
+	"trawler/pkg/storage"
 
 	"github.com/minio/minio-go/v7"
 	"github.com/minio/minio-go/v7/pkg/credentials"
@@ -127,3 +129,88 @@ func MinIOCreateS3Bucket(ctx context.Context, client *minio.Client, bucketName s
 
 	return nil
 }
+
+// MinIOBackend publishes objects to a bucket on a MinIO server
+type MinIOBackend struct {
+	name   string
+	client *minio.Client
+	bucket string
+}
+
+// NewMinIOBackend connects to MinIO and creates a storage backend for the bucket set in the config
+func NewMinIOBackend(name string, config *S3Config) (*MinIOBackend, error) {
+	client, err := MinIOConnectToS3(config)
+	if err != nil {
+		return nil, err
+	}
+	return &MinIOBackend{name: name, client: client, bucket: config.Bucket}, nil
+}
+
+func (b *MinIOBackend) Name() string {
+	return b.name
+}
+
+func (b *MinIOBackend) Get(ctx context.Context, key string) ([]byte, error) {
+	object, err := b.client.GetObject(ctx, b.bucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, minioTranslateError(err)
+	}
+	defer object.Close()
+
+	// The object is fetched lazily, so a missing key is first reported when reading
+	data, err := io.ReadAll(object)
+	if err != nil {
+		return nil, minioTranslateError(err)
+	}
+	return data, nil
+}
+
+func (b *MinIOBackend) Put(ctx context.Context, key string, data []byte) error {
+	_, err := b.client.PutObject(ctx, b.bucket, key, bytes.NewReader(data), int64(len(data)),
+		minio.PutObjectOptions{ContentType: objectContentType(key, data)})
+	if err != nil {
+		return fmt.Errorf("Failed to upload object to bucket %s with key %s: %v", b.bucket, key, err)
+	}
+	return nil
+}
+
+func (b *MinIOBackend) Stat(ctx context.Context, key string) (*storage.ObjectInfo, error) {
+	info, err := b.client.StatObject(ctx, b.bucket, key, minio.StatObjectOptions{})
+	if err != nil {
+		return nil, minioTranslateError(err)
+	}
+	return &storage.ObjectInfo{Key: info.Key, Size: info.Size, LastModified: info.LastModified}, nil
+}
+
+func (b *MinIOBackend) List(ctx context.Context, prefix string) ([]storage.ObjectInfo, error) {
+	var objects []storage.ObjectInfo
+	for info := range b.client.ListObjects(ctx, b.bucket, minio.ListObjectsOptions{Prefix: prefix, Recursive: true}) {
+		if info.Err != nil {
+			return nil, info.Err
+		}
+		objects = append(objects, storage.ObjectInfo{Key: info.Key, Size: info.Size, LastModified: info.LastModified})
+	}
+	return objects, nil
+}
+
+func (b *MinIOBackend) Delete(ctx context.Context, key string) error {
+	return b.client.RemoveObject(ctx, b.bucket, key, minio.RemoveObjectOptions{})
+}
+
+func (b *MinIOBackend) HealthCheck(ctx context.Context) error {
+	exist, err := MinIOExistS3Bucket(ctx, b.client, b.bucket)
+	if err != nil {
+		return err
+	}
+	if !exist {
+		return fmt.Errorf("bucket %s does not exist", b.bucket)
+	}
+	return nil
+}
+
+func minioTranslateError(err error) error {
+	if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+		return storage.ErrNotFound
+	}
+	return err
+}
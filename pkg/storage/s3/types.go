@@ -1,7 +1,10 @@
 package s3
 
 import (
+	"path"
+	"strings"
 	"time"
+	"trawler/pkg/crl"
 
 	awsS3 "github.com/aws/aws-sdk-go-v2/service/s3"
 )
@@ -14,6 +17,7 @@ type S3Config struct {
 	AuthEndpoint      string
 	ServiceEndpoint   string
 	Region            string
+	Bucket            string
 }
 
 type S3Authentication struct {
@@ -51,4 +55,32 @@ const (
 	ServiceIBM     S3Service = "IBM"
 	ServiceMinIO   S3Service = "MinIO"
 	ServiceGeneric S3Service = "Generic"
+	ServiceAWS     S3Service = "AWS"
 )
+
+// Content types of the objects uploaded to S3
+const (
+	crlContentType     = "application/pkix-crl"
+	pemContentType     = "application/x-pem-file"
+	jsonContentType    = "application/json"
+	defaultContentType = "application/octet-stream"
+)
+
+// objectContentType returns the content type of an object from the suffix of its key,
+// so that PEM copies and JSON metadata are not served as DER CRLs.
+// A backend with the pem format stores PEM under the .crl key, which is recognized from the data.
+func objectContentType(key string, data []byte) string {
+	switch strings.ToLower(path.Ext(key)) {
+	case ".crl":
+		if crl.IsPEM(data) {
+			return pemContentType
+		}
+		return crlContentType
+	case ".pem":
+		return pemContentType
+	case ".json":
+		return jsonContentType
+	default:
+		return defaultContentType
+	}
+}
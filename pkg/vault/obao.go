@@ -1,6 +1,7 @@
 package vault
 
 import (
+	"context"
 	"fmt"
 	"os"
 
@@ -18,7 +19,8 @@ var client *VaultClient
 func init() {
 	// Initialize Vault client and set it as the default client for the package
 	defaultVaultConfig := vault.DefaultConfig()
-	client, err := vault.NewClient(defaultVaultConfig)
+	var err error
+	client, err = vault.NewClient(defaultVaultConfig)
 	if err != nil {
 		panic("Failed to initialize Vault client: " + err.Error())
 	}
@@ -62,3 +64,18 @@ func GetVaultSecret(secretPath string) (map[string]interface{}, error) {
 
 	return secret.Data, nil
 }
+
+// HealthCheck verifies that Vault is reachable, initialized and unsealed
+func HealthCheck(ctx context.Context) error {
+	healthResponse, err := client.Sys().HealthWithContext(ctx)
+	if err != nil {
+		return err
+	}
+	if !healthResponse.Initialized {
+		return fmt.Errorf("vault is not initialized")
+	}
+	if healthResponse.Sealed {
+		return fmt.Errorf("vault is sealed")
+	}
+	return nil
+}
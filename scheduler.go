@@ -0,0 +1,77 @@
+package main
+
+import (
+	"sync"
+	"time"
+	cfg "trawler/pkg/config"
+	crl "trawler/pkg/crl"
+)
+
+// Defaults for adaptive scheduling, used when not set in config
+const (
+	defaultLeadThresholdMinutes = 60
+	defaultMinIntervalMinutes   = 2
+	defaultMaxIntervalMinutes   = 240
+	scheduleCheckInterval       = 15 * time.Second // How often the worker looks for CRLs that are due
+)
+
+// crlScheduler keeps track of when each online CRL should be fetched next
+type crlScheduler struct {
+	mu        sync.Mutex
+	nextFetch map[string]time.Time
+}
+
+var scheduler = &crlScheduler{nextFetch: make(map[string]time.Time)}
+
+// IsDue reports whether the CRL should be fetched now. CRLs that have not been fetched yet are always due.
+func (s *crlScheduler) IsDue(config *cfg.Config, crlName string, now time.Time) bool {
+	if !config.Configurations.Scheduling.Enabled {
+		return true
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	nextFetch, exists := s.nextFetch[crlName]
+	return !exists || !now.Before(nextFetch)
+}
+
+// Schedule sets the next fetch time of a CRL from its timestamps. Zero timestamps mean the fetch failed.
+func (s *crlScheduler) Schedule(config *cfg.Config, crlName string, nextUpdate time.Time, nextCRLPublish time.Time) time.Time {
+	now := time.Now()
+	nextFetch := crl.NextFetchTime(now, nextUpdate, nextCRLPublish, scheduleConfig(config))
+
+	s.mu.Lock()
+	s.nextFetch[crlName] = nextFetch
+	s.mu.Unlock()
+	return nextFetch
+}
+
+// NextFetch returns the scheduled fetch time of a CRL, or the zero time if it has not been scheduled
+func (s *crlScheduler) NextFetch(crlName string) time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.nextFetch[crlName]
+}
+
+func scheduleConfig(config *cfg.Config) crl.ScheduleConfig {
+	scheduling := config.Configurations.Scheduling
+
+	leadThresholdMinutes := scheduling.LeadThresholdMinutes
+	if leadThresholdMinutes <= 0 {
+		leadThresholdMinutes = defaultLeadThresholdMinutes
+	}
+	minIntervalMinutes := scheduling.MinIntervalMinutes
+	if minIntervalMinutes <= 0 {
+		minIntervalMinutes = defaultMinIntervalMinutes
+	}
+	maxIntervalMinutes := scheduling.MaxIntervalMinutes
+	if maxIntervalMinutes <= 0 {
+		maxIntervalMinutes = defaultMaxIntervalMinutes
+	}
+
+	return crl.ScheduleConfig{
+		LeadThreshold: time.Duration(leadThresholdMinutes) * time.Minute,
+		MinInterval:   time.Duration(minIntervalMinutes) * time.Minute,
+		MaxInterval:   time.Duration(maxIntervalMinutes) * time.Minute,
+		JitterPercent: scheduling.JitterPercent,
+	}
+}
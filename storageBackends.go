@@ -0,0 +1,166 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"sync/atomic"
+	cfg "trawler/pkg/config"
+	logging "trawler/pkg/logging"
+	"trawler/pkg/storage"
+	"trawler/pkg/storage/s3"
+)
+
+// Storage backend types supported in the config
+const (
+	storageTypeLocal = "local"
+	storageTypeAWS   = "aws"
+	storageTypeMinIO = "minio"
+	storageTypeIBM   = "ibm"
+)
+
+// storageSettings are the parts of config the storage backends are built from
+type storageSettings struct {
+	Backends            []cfg.StorageBackend
+	LocalStorageEnabled bool
+	OnlineCrlsPath      string
+}
+
+var storageBackends atomic.Pointer[[]storage.Backend] // Storage backends CRLs are published to
+var storageBackendsConfig storageSettings             // Settings the current storage backends were built from
+
+// currentStorageBackends returns the storage backends CRLs are currently published to
+func currentStorageBackends() []storage.Backend {
+	if backends := storageBackends.Load(); backends != nil {
+		return *backends
+	}
+	return nil
+}
+
+// updateStorageBackends (re)creates the storage backends when their settings in config change.
+// Readers holding the previous backends keep using them until they call currentStorageBackends again.
+func updateStorageBackends(config *cfg.Config) {
+	settings := storageSettings{
+		Backends:            config.Configurations.Storage.Backends,
+		LocalStorageEnabled: config.Configurations.Global.LocalStorageEnabled,
+		OnlineCrlsPath:      config.Configurations.Global.OnlineCrlsPath,
+	}
+	if storageBackends.Load() != nil && reflect.DeepEqual(settings, storageBackendsConfig) {
+		return
+	}
+	backends := initStorageBackends(config)
+	if len(backends) == 0 {
+		logging.LogToConsole(logging.WarningLevel, logging.WarningEvent, "No storage backends enabled, CRLs will be validated but not published.")
+	}
+	storageBackends.Store(&backends)
+	storageBackendsConfig = settings
+} // func updateStorageBackends
+
+// initStorageBackends creates the storage backends defined in config.
+// If none are defined, the legacy settings (localStorageEnabled and the AWS_S3_* environment variables) are used.
+func initStorageBackends(config *cfg.Config) []storage.Backend {
+	var backends []storage.Backend
+
+	if len(config.Configurations.Storage.Backends) == 0 {
+		return legacyStorageBackends(config)
+	}
+
+	for _, backendConfig := range config.Configurations.Storage.Backends {
+		backend, err := newStorageBackend(backendConfig)
+		if err != nil {
+			logging.LogToConsole(logging.ErrorLevel, logging.ErrorEvent, fmt.Sprintf("Failed to initialize storage backend %s: %v", backendConfig.Name, err))
+			continue
+		}
+		formatted, err := storage.WithFormat(storage.WithPrefix(backend, backendConfig.Prefix), backendConfig.Format)
+		if err != nil {
+			logging.LogToConsole(logging.ErrorLevel, logging.ErrorEvent, fmt.Sprintf("Failed to initialize storage backend %s: %v", backendConfig.Name, err))
+			continue
+		}
+		backends = append(backends, formatted)
+		logging.LogToConsole(logging.InfoLevel, logging.InfoEvent, fmt.Sprintf("Storage backend %s (%s) initialized.", backend.Name(), backendConfig.Type))
+	}
+	return backends
+}
+
+func newStorageBackend(backendConfig cfg.StorageBackend) (storage.Backend, error) {
+	name := backendConfig.Name
+	if name == "" {
+		name = backendConfig.Type
+	}
+
+	s3Config := &s3.S3Config{
+		APIKey:            backendConfig.AccessKeyID,
+		APISecret:         []byte(backendConfig.SecretAccessKey),
+		SSLEnabled:        backendConfig.UseSSL,
+		ServiceInstanceID: backendConfig.ServiceInstanceID,
+		AuthEndpoint:      backendConfig.AuthEndpoint,
+		ServiceEndpoint:   backendConfig.Endpoint,
+		Region:            backendConfig.Region,
+		Bucket:            backendConfig.Bucket,
+	}
+
+	switch backendConfig.Type {
+	case storageTypeLocal:
+		if backendConfig.Path == "" {
+			return nil, fmt.Errorf("path is required for local storage")
+		}
+		return storage.NewLocalBackend(name, backendConfig.Path), nil
+	case storageTypeAWS:
+		if backendConfig.Bucket == "" {
+			return nil, fmt.Errorf("bucket is required for AWS S3 storage")
+		}
+		client, err := s3.AWSCreateS3ClientFromConfig(s3Config)
+		if err != nil {
+			return nil, err
+		}
+		return s3.NewAWSBackend(name, client, backendConfig.Bucket), nil
+	case storageTypeMinIO:
+		if backendConfig.Bucket == "" || backendConfig.Endpoint == "" {
+			return nil, fmt.Errorf("bucket and endpoint are required for MinIO storage")
+		}
+		if s3Config.APIKey == "" {
+			s3Config.APIKey = os.Getenv("MINIO_S3_API_KEY_ID")
+			s3Config.APISecret = []byte(os.Getenv("MINIO_S3_API_KEY_SECRET"))
+		}
+		return s3.NewMinIOBackend(name, s3Config)
+	case storageTypeIBM:
+		if backendConfig.Bucket == "" || backendConfig.Endpoint == "" {
+			return nil, fmt.Errorf("bucket and endpoint are required for IBM COS storage")
+		}
+		if s3Config.APIKey == "" {
+			s3Config.APIKey = os.Getenv("IBM_COS_API_KEY_ID")
+		}
+		if s3Config.ServiceInstanceID == "" {
+			s3Config.ServiceInstanceID = os.Getenv("IBM_COS_SERVICE_INSTANCE_ID")
+		}
+		if s3Config.AuthEndpoint == "" {
+			s3Config.AuthEndpoint = os.Getenv("IBM_COS_AUTH_ENDPOINT")
+		}
+		return s3.NewIBMBackend(name, s3Config), nil
+	default:
+		return nil, fmt.Errorf("unsupported storage type: %q", backendConfig.Type)
+	}
+}
+
+func legacyStorageBackends(config *cfg.Config) []storage.Backend {
+	var backends []storage.Backend
+
+	if config.Configurations.Global.LocalStorageEnabled {
+		backends = append(backends, storage.NewLocalBackend("Local", config.Configurations.Global.OnlineCrlsPath))
+	}
+
+	// Initialize S3 client if S3 storage is enabled and configuration is valid
+	s3Config, err := s3.GetS3Config()
+	if err != nil && s3Config == nil {
+		logging.LogToConsole(logging.ErrorLevel, logging.ErrorEvent, fmt.Sprintf("S3 configuration validation failed: %v", err))
+	} else if s3Config != nil {
+		logging.LogToConsole(logging.InfoLevel, logging.InfoEvent, "S3 storage enabled and configuration validated successfully.")
+		s3Client, err := s3.AWSCreateS3Client()
+		if err != nil {
+			logging.LogToConsole(logging.ErrorLevel, logging.ErrorEvent, fmt.Sprintf("Failed to create S3 client: %v", err))
+		} else {
+			backends = append(backends, s3.NewAWSBackend("S3", s3Client, os.Getenv("AWS_S3_BUCKET_NAME")))
+		}
+	}
+	return backends
+}